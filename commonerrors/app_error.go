@@ -0,0 +1,39 @@
+package commonerrors
+
+// AppError 是携带 HTTP 状态码、业务错误码和对外消息的类型化错误
+// - Status / Code: 返回给客户端的 HTTP 状态码和 response 包中的业务错误码
+// - Message: 可以直接展示给客户端的消息
+// - Err: 内部原因，只用于日志和链路，不会返回给客户端
+//
+// 处理程序通过 c.Error(err) 交给 ErrorMappingMiddleware 统一写出响应，例如:
+//
+//	c.Error(commonerrors.WrapAppError(err, http.StatusBadRequest, response.ErrCodeClientInvalidInput, "手机号格式不正确"))
+type AppError struct {
+	Status  int
+	Code    int
+	Message string
+	Err     error
+}
+
+// NewAppError 创建不带内部原因的 AppError
+func NewAppError(status, code int, message string) *AppError {
+	return &AppError{Status: status, Code: code, Message: message}
+}
+
+// WrapAppError 用对外的状态码、错误码和消息包装内部错误，errors.Is / errors.As 仍然可以匹配到 err
+func WrapAppError(err error, status, code int, message string) *AppError {
+	return &AppError{Status: status, Code: code, Message: message, Err: err}
+}
+
+// Error 实现 error 接口，包含内部原因，只应出现在日志中
+func (e *AppError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap 返回内部原因
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
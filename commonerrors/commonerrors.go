@@ -11,3 +11,5 @@ var ErrSystemError = errors.New("系统错误，请稍后重试")
 var ErrUserNotLoggedIn = errors.New("用户未登录") // 新增：用户未登录错误
 
 var ErrThirdPartyServiceError = errors.New("第三方服务出现故障")
+
+var ErrVersionConflict = errors.New("数据已被修改，请刷新后重试") // 乐观锁版本冲突
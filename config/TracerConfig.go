@@ -4,9 +4,52 @@ package config
 type TracerConfig struct {
 	Enabled bool `mapstructure:"enabled" yaml:"enabled"` // 是否启用追踪
 	// ServiceName 会由各个服务自己定义，不放在这里
-	ExporterType     string `mapstructure:"exporter_type" yaml:"exporter_type"`         // Exporter 类型: "otlp_grpc", "otlp_http", "stdout", "jaeger" 等
-	ExporterEndpoint string `mapstructure:"exporter_endpoint" yaml:"exporter_endpoint"` // Exporter 地址 (e.g., "otel-collector:4317" for grpc, "otel-collector:4318" for http)
+	ExporterType     string `mapstructure:"exporter_type" yaml:"exporter_type"`         // Exporter 类型: "otlp_grpc", "otlp_http", "stdout", "zipkin", "file", "multi" (Jaeger 可直接使用 otlp_grpc/otlp_http 接收)
+	ExporterEndpoint string `mapstructure:"exporter_endpoint" yaml:"exporter_endpoint"` // Exporter 地址 (e.g., "otel-collector:4317" for grpc, "otel-collector:4318" for http, "http://zipkin:9411/api/v2/spans" for zipkin)
 	// ExporterTimeout // 可以添加导出超时等配置
-	SamplerType  string  `mapstructure:"sampler_type" yaml:"sampler_type"`   // 采样器类型: "always_on", "always_off", "traceid_ratio", "parent_based_traceid_ratio"
-	SamplerParam float64 `mapstructure:"sampler_param" yaml:"sampler_param"` // 采样器参数 (e.g., for traceid_ratio, 0.1 means 10%)
+
+	// Exporters 在 ExporterType 为 "multi" 时同时使用的多个 Exporter (e.g., OTLP + file)，每个 Span 会扇出到所有 Exporter
+	Exporters []ExporterConfig `mapstructure:"exporters" yaml:"exporters"`
+	// File 文件 Exporter 的配置，在 ExporterType (或 Exporters 中某一项) 为 "file" 时生效
+	File         FileExporterConfig `mapstructure:"file" yaml:"file"`
+	SamplerType  string             `mapstructure:"sampler_type" yaml:"sampler_type"`   // 采样器类型: "always_on", "always_off", "traceid_ratio", "parent_based_traceid_ratio", "rule_based", "tail_based"
+	SamplerParam float64            `mapstructure:"sampler_param" yaml:"sampler_param"` // 采样器参数 (e.g., for traceid_ratio, 0.1 means 10%)
+
+	// Resource 控制 Resource 属性 (部署环境、主机、容器、K8s 等) 的检测
+	Resource ResourceConfig `mapstructure:"resource" yaml:"resource"`
+
+	// SamplingRules 按路由的采样规则，仅在 SamplerType 为 "rule_based" 或 "tail_based" 时生效
+	// 规则按顺序匹配，第一条命中的规则决定采样结果；都不命中时回退到默认采样器
+	SamplingRules []SamplingRule `mapstructure:"sampling_rules" yaml:"sampling_rules"`
+	// TailSampling 尾部采样配置，仅在 SamplerType 为 "tail_based" 时生效
+	TailSampling TailSamplingConfig `mapstructure:"tail_sampling" yaml:"tail_sampling"`
+}
+
+// ExporterConfig 定义 "multi" 模式下的单个 Exporter
+type ExporterConfig struct {
+	Type     string `mapstructure:"type" yaml:"type"`         // Exporter 类型: "otlp_grpc", "otlp_http", "stdout", "zipkin", "file"
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint"` // Exporter 地址，含义同 TracerConfig.ExporterEndpoint ("stdout"/"file" 不需要)
+}
+
+// FileExporterConfig 定义 JSON Lines 文件 Exporter 的配置，用于本地调试或离线分析
+// 每个 Span 写为一行 JSON，文件超过 MaxSizeMB 时轮转为 <path>.<时间戳>
+type FileExporterConfig struct {
+	Path       string `mapstructure:"path" yaml:"path"`               // 输出文件路径 (e.g., "./traces/spans.jsonl")
+	MaxSizeMB  int    `mapstructure:"max_size_mb" yaml:"max_size_mb"` // 单个文件的最大大小 (MB)，<= 0 时使用默认值 100
+	MaxBackups int    `mapstructure:"max_backups" yaml:"max_backups"` // 保留的轮转文件数量，<= 0 表示全部保留
+}
+
+// SamplingRule 定义一条按路由的采样规则
+// 例如: {path: "/healthz", ratio: 0} 表示从不采样健康检查；{path: "/api/v1/posts", methods: ["POST","PUT"], ratio: 1} 表示总是采样帖子写操作
+type SamplingRule struct {
+	Path    string   `mapstructure:"path" yaml:"path"`       // 路由路径，精确匹配；以 "*" 结尾时按前缀匹配 (e.g., "/api/v1/posts/*")
+	Methods []string `mapstructure:"methods" yaml:"methods"` // HTTP 方法列表 (e.g., ["POST", "PUT"])，为空表示匹配所有方法
+	Ratio   float64  `mapstructure:"ratio" yaml:"ratio"`     // 采样比例: 1 表示总是采样，0 表示从不采样，介于两者之间按 TraceID 比例采样
+}
+
+// TailSamplingConfig 定义尾部采样 (在 SpanProcessor 层面按本地 Trace 整体决策) 的配置
+// 本地 Trace 的所有 Span 结束后，包含错误或总耗时超过阈值的 Trace 会被完整保留，其余丢弃
+type TailSamplingConfig struct {
+	LatencyThresholdMs int `mapstructure:"latency_threshold_ms" yaml:"latency_threshold_ms"` // 耗时阈值 (毫秒)，任一 Span 超过该值则保留整个 Trace，<= 0 表示只按错误保留
+	MaxPendingTraces   int `mapstructure:"max_pending_traces" yaml:"max_pending_traces"`     // 内存中最多缓存的未完成 Trace 数量，超出时提前对最旧的 Trace 做决策 (默认 10000)
 }
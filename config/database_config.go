@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// DatabaseConfig 定义数据库连接 (主库 + 只读从库) 的配置项
+// 对应 docker-compose 中的 mysql-primary / mysql-replica，读请求路由到从库，写请求和事务路由到主库
+type DatabaseConfig struct {
+	PrimaryDSN    string   `mapstructure:"primaryDSN" yaml:"primaryDSN"`       // 主库 DSN (e.g., "root:root@tcp(mysql-primary:3306)/doer?charset=utf8mb4&parseTime=True&loc=Local")
+	ReplicaDSNs   []string `mapstructure:"replicaDSNs" yaml:"replicaDSNs"`     // 从库 DSN 列表，为空时所有读写都走主库
+	ReplicaPolicy string   `mapstructure:"replicaPolicy" yaml:"replicaPolicy"` // 多个从库间的负载均衡策略: "random" (默认), "round_robin"
+
+	// 连接池配置，同时作用于主库和每个从库的连接池
+	MaxOpenConns    int           `mapstructure:"maxOpenConns" yaml:"maxOpenConns"`       // 最大打开连接数，<= 0 表示不限制
+	MaxIdleConns    int           `mapstructure:"maxIdleConns" yaml:"maxIdleConns"`       // 最大空闲连接数，<= 0 时使用 database/sql 的默认值 2
+	ConnMaxLifetime time.Duration `mapstructure:"connMaxLifetime" yaml:"connMaxLifetime"` // 连接最长存活时间 (e.g., "1h")，<= 0 表示不限制
+	ConnMaxIdleTime time.Duration `mapstructure:"connMaxIdleTime" yaml:"connMaxIdleTime"` // 连接最长空闲时间 (e.g., "10m")，<= 0 表示不限制
+}
@@ -6,4 +6,39 @@ type GormLogConfig struct {
 	SlowThresholdMs           int    `mapstructure:"slowThresholdMs" yaml:"slowThresholdMs"`                     // 慢查询阈值 (毫秒)
 	SkipCallerLookup          bool   `mapstructure:"skipCallerLookup" yaml:"skipCallerLookup"`                   // 是否跳过 GORM 的调用者信息查找 (提升性能)
 	IgnoreRecordNotFoundError bool   `mapstructure:"ignoreRecordNotFoundError" yaml:"ignoreRecordNotFoundError"` // 是否忽略 'record not found' 错误 (通常为 true)
+	TraceRedactParams         bool   `mapstructure:"traceRedactParams" yaml:"traceRedactParams"`                 // 追踪插件是否在 db.statement 中隐藏 SQL 参数值 (只保留占位符)
+
+	// SQL 脱敏与截断
+	ParameterizedQueries bool                `mapstructure:"parameterizedQueries" yaml:"parameterizedQueries"` // 是否只记录带占位符的 SQL (不把参数值插入语句)
+	LogQueryArgs         bool                `mapstructure:"logQueryArgs" yaml:"logQueryArgs"`                 // ParameterizedQueries 模式下是否把 (脱敏后的) 参数单独记录到 args 字段
+	MaxStatementLength   int                 `mapstructure:"maxStatementLength" yaml:"maxStatementLength"`     // SQL 语句最大长度 (字节)，超出部分截断并追加标记，<= 0 表示不截断
+	RedactColumns        map[string][]string `mapstructure:"redactColumns" yaml:"redactColumns"`               // 按表配置需要脱敏的列 (表名 -> 列名列表)，表名 "*" 表示对所有表生效 (e.g., {"users": ["password_hash", "phone"]})
+
+	// 慢查询聚合
+	SlowQueryAggregation       bool `mapstructure:"slowQueryAggregation" yaml:"slowQueryAggregation"`             // 是否按语句指纹 (字面量归一化) 聚合慢查询，并定期输出汇总日志
+	SlowQueryReportIntervalSec int  `mapstructure:"slowQueryReportIntervalSec" yaml:"slowQueryReportIntervalSec"` // 汇总日志的输出间隔 (秒)，<= 0 时默认 60
+	LogEachSlowQuery           bool `mapstructure:"logEachSlowQuery" yaml:"logEachSlowQuery"`                     // 开启聚合后是否仍然逐条输出慢查询 Warn 日志
+
+	FieldNames GormLogFieldNames `mapstructure:"fieldNames" yaml:"fieldNames"` // 日志字段名，未配置的字段使用 OTel 风格的默认键名
+
+	Debug SQLDebugConfig `mapstructure:"debug" yaml:"debug"` // 单个请求临时提升 SQL 日志级别 (middleware.SQLDebugMiddleware)
+}
+
+// SQLDebugConfig 定义按请求覆盖 GORM 日志级别的触发条件
+// 满足任一条件的请求，其 SQL 日志按 Level 输出，其他请求仍使用 GormLogConfig.Level
+type SQLDebugConfig struct {
+	Header     string   `mapstructure:"header" yaml:"header"`         // 调试请求头名称，默认 "X-Debug-SQL"
+	Token      string   `mapstructure:"token" yaml:"token"`           // 请求头的值必须与该令牌一致才被信任；为空时不接受请求头触发
+	AdminRoles []string `mapstructure:"adminRoles" yaml:"adminRoles"` // 具有这些角色 (UserContextMiddleware 写入的 Role) 的请求自动开启，为空时不按角色触发
+	Level      string   `mapstructure:"level" yaml:"level"`           // 覆盖后的 GORM 日志级别，默认 "info"
+}
+
+// GormLogFieldNames 定义 GormLogger 输出结构化日志时使用的字段名
+// 默认值与 RequestLoggerMiddleware 的 http.* 键风格保持一致，便于在 Elasticsearch 中统一查询
+type GormLogFieldNames struct {
+	Statement    string `mapstructure:"statement" yaml:"statement"`       // SQL 语句字段名，默认 "db.statement"
+	RowsAffected string `mapstructure:"rowsAffected" yaml:"rowsAffected"` // 影响行数字段名，默认 "db.rows_affected"
+	Duration     string `mapstructure:"duration" yaml:"duration"`         // 耗时字段名 (数值，单位毫秒)，默认 "duration_ms"
+	Caller       string `mapstructure:"caller" yaml:"caller"`             // 调用者字段名，默认 "code.caller"
+	Args         string `mapstructure:"args" yaml:"args"`                 // SQL 参数字段名 (仅 LogQueryArgs 时输出)，默认 "db.statement.args"
 }
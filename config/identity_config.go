@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// IdentitySigningConfig 定义网关与下游服务之间身份请求头的 HMAC 签名配置
+// 轮换密钥的步骤: 先在所有服务的 Keys 中加入新密钥，再把网关的 KeyID 切换为新密钥，最后从 Keys 中移除旧密钥。
+type IdentitySigningConfig struct {
+	KeyID        string            `mapstructure:"keyID" yaml:"keyID"`               // 签名方 (网关) 当前使用的密钥 ID，必须存在于 Keys 中；只做校验的服务可以不配置
+	Keys         map[string]string `mapstructure:"keys" yaml:"keys"`                 // 密钥 ID -> 密钥 (至少 32 字节)，校验方接受其中任意一个密钥的签名
+	ReplayWindow time.Duration     `mapstructure:"replayWindow" yaml:"replayWindow"` // 签名时间戳与当前时间允许的最大偏差，超出视为重放，<= 0 时默认 60s
+}
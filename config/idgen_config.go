@@ -0,0 +1,6 @@
+package config
+
+// IDGenConfig 定义分布式 ID 生成器的配置项
+type IDGenConfig struct {
+	NodeID int64 `mapstructure:"nodeID" yaml:"nodeID"` // Snowflake 节点 ID (0-1023)，同一时刻运行的每个实例必须不同 (e.g., 使用 StatefulSet 序号)
+}
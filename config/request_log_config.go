@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// RequestLogConfig 定义 RequestLoggerMiddleware 的行为，零值表示记录所有请求 (2xx/3xx 为 Info，4xx 为 Warn，5xx 为 Error)
+type RequestLogConfig struct {
+	SkipPaths         []string      `mapstructure:"skipPaths" yaml:"skipPaths"`                 // 不记录的路径 (Gin 路由模式或 URL 路径，以 "*" 结尾表示前缀匹配)，例如健康检查和静态资源
+	SkipPathRegexps   []string      `mapstructure:"skipPathRegexps" yaml:"skipPathRegexps"`     // 不记录的 URL 路径正则 (e.g., "\\.(js|css|png)$")
+	SuccessSampleRate float64       `mapstructure:"successSampleRate" yaml:"successSampleRate"` // 2xx/3xx 请求的采样率 (0, 1)，<= 0 或 >= 1 时全部记录；4xx/5xx 和慢请求始终记录
+	SlowThreshold     time.Duration `mapstructure:"slowThreshold" yaml:"slowThreshold"`         // 慢请求阈值，超过时日志级别至少提升为 Warn 并标记 slow=true，<= 0 表示不判断
+
+	Capture RequestCaptureConfig `mapstructure:"capture" yaml:"capture"` // 额外采集的请求/响应信息
+}
+
+// Validate 检查配置是否有效: SkipPathRegexps 中的每个正则都必须能编译。
+// core.LoadConfig 在解析配置后会自动调用，使配置错误在加载阶段暴露。
+func (c RequestLogConfig) Validate() error {
+	for _, expr := range c.SkipPathRegexps {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("request_log.skipPathRegexps 中的正则 %q 无效: %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// RequestCaptureConfig 定义 RequestLoggerMiddleware 额外采集的请求/响应信息，全部默认关闭
+type RequestCaptureConfig struct {
+	Sizes        bool     `mapstructure:"sizes" yaml:"sizes"`               // 是否记录请求/响应体字节数
+	Query        bool     `mapstructure:"query" yaml:"query"`               // 是否记录查询字符串 (RedactKeys 中的参数会被脱敏)
+	Route        bool     `mapstructure:"route" yaml:"route"`               // 是否记录路由模板 (e.g., "/api/v1/posts/:id")
+	RequestBody  bool     `mapstructure:"requestBody" yaml:"requestBody"`   // 是否记录请求体
+	ResponseBody bool     `mapstructure:"responseBody" yaml:"responseBody"` // 是否记录响应体
+	MaxBodyBytes int      `mapstructure:"maxBodyBytes" yaml:"maxBodyBytes"` // 每个请求/响应体最多记录的字节数，<= 0 时默认 4096
+	ContentTypes []string `mapstructure:"contentTypes" yaml:"contentTypes"` // 只记录这些媒体类型的请求/响应体，为空时默认 ["application/json"]
+	RedactKeys   []string `mapstructure:"redactKeys" yaml:"redactKeys"`     // 需要脱敏的 JSON 键/表单字段/查询参数 (不区分大小写)，为空时默认 ["password", "contact_info", "token"]
+
+	// 请求/响应体的记录范围 (大小、查询字符串和路由模板不受限制)
+	Routes        []string `mapstructure:"routes" yaml:"routes"`               // 只记录这些路由的请求/响应体 (Gin 路由模式，以 "*" 结尾表示前缀匹配)，为空表示所有路由
+	StatusClasses []string `mapstructure:"statusClasses" yaml:"statusClasses"` // 只记录这些状态码类别的请求/响应体 (e.g., ["4xx", "5xx"])，为空表示所有状态码
+}
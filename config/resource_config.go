@@ -0,0 +1,12 @@
+package config
+
+// ResourceConfig 定义遥测数据 (Trace / Metric / Log) 共用的 Resource 属性检测选项
+// 默认会检测所有支持的来源，只有显式关闭的才会跳过
+type ResourceConfig struct {
+	Environment      string            `mapstructure:"environment" yaml:"environment"`             // 部署环境 (e.g., "dev", "staging", "prod")，为空时读取环境变量 DEPLOY_ENV
+	DisableHost      bool              `mapstructure:"disable_host" yaml:"disable_host"`           // 是否关闭主机名检测 (host.name)
+	DisableContainer bool              `mapstructure:"disable_container" yaml:"disable_container"` // 是否关闭容器 ID 检测 (从 cgroup 读取 container.id)
+	DisableK8s       bool              `mapstructure:"disable_k8s" yaml:"disable_k8s"`             // 是否关闭 K8s 属性检测 (从 Downward API 注入的环境变量读取 pod/namespace/node)
+	DisableProcess   bool              `mapstructure:"disable_process" yaml:"disable_process"`     // 是否关闭进程运行时信息检测 (pid, Go 版本等)
+	Attributes       map[string]string `mapstructure:"attributes" yaml:"attributes"`               // 额外的静态属性，优先级低于 OTEL_RESOURCE_ATTRIBUTES 环境变量
+}
@@ -3,7 +3,26 @@ package config
 import "time"
 
 type ServerConfig struct {
-	ListenAddr     string        `mapstructure:"listen_addr" yaml:"listen_addr"` // 添加监听地址
-	Port           string        `mapstructure:"port" yaml:"port"`
-	RequestTimeout time.Duration `mapstructure:"requestTimeout" yaml:"requestTimeout"`
+	ListenAddr     string              `mapstructure:"listen_addr" yaml:"listen_addr"` // 添加监听地址
+	Port           string              `mapstructure:"port" yaml:"port"`
+	RequestTimeout time.Duration       `mapstructure:"requestTimeout" yaml:"requestTimeout"`
+	TimeoutPolicy  TimeoutPolicyConfig `mapstructure:"timeoutPolicy" yaml:"timeoutPolicy"` // 按路由的超时策略 (middleware.TimeoutPolicyMiddleware)
+}
+
+// TimeoutPolicyConfig 定义按路由和方法区分的请求超时策略
+// 规则按顺序匹配，第一条命中的规则决定超时；都不命中时使用 Default
+type TimeoutPolicyConfig struct {
+	Default      time.Duration       `mapstructure:"default" yaml:"default"`           // 未命中规则时的超时，<= 0 时使用 ServerConfig.RequestTimeout
+	Max          time.Duration       `mapstructure:"max" yaml:"max"`                   // 开启 allowClientExtend 的规则允许客户端延长到的上限，<= 0 时不允许延长
+	ClientHeader string              `mapstructure:"clientHeader" yaml:"clientHeader"` // 客户端指定超时的请求头，默认 "X-Request-Timeout" (值为 "1500ms"、"2s" 或毫秒数)
+	Rules        []TimeoutRuleConfig `mapstructure:"rules" yaml:"rules"`
+}
+
+// TimeoutRuleConfig 定义单条超时规则
+type TimeoutRuleConfig struct {
+	Path    string   `mapstructure:"path" yaml:"path"`       // Gin 路由模式 (e.g., "/api/v1/posts/:id")，以 "*" 结尾表示前缀匹配 (e.g., "/api/v1/upload/*")
+	Methods []string `mapstructure:"methods" yaml:"methods"` // 匹配的 HTTP 方法，为空表示所有方法
+	Timeout string   `mapstructure:"timeout" yaml:"timeout"` // 超时时间 (e.g., "30s")，"none" 表示不设置超时 (文件上传、SSE 等)
+	// 是否允许客户端通过请求头把超时延长到 TimeoutPolicyConfig.Max；默认客户端只能缩短超时
+	AllowClientExtend bool `mapstructure:"allowClientExtend" yaml:"allowClientExtend"`
 }
@@ -6,3 +6,4 @@ const UserIDKey contextKey = "UserID"
 const RoleKey contextKey = "Role"
 const StatusKey contextKey = "Status"
 const PlatformKey contextKey = "Platform"
+const UserContextKey contextKey = "UserContext" // 解析校验后的 identity.UserContext (即 middleware.UserContext)
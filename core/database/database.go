@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 读写分离使用的从库负载均衡策略
+const (
+	ReplicaPolicyRandom     = "random"
+	ReplicaPolicyRoundRobin = "round_robin"
+)
+
+// forcePrimaryCallbackName 是强制读主库的回调名称，注册在 dbresolver 的路由回调之后、执行 SQL 之前
+const forcePrimaryCallbackName = "go-common:database:force_primary"
+
+// Option 定义 Open 的可选配置项
+type Option func(*options)
+
+// options 保存 Open 的可选配置
+type options struct {
+	dialector  func(dsn string) gorm.Dialector
+	gormConfig *gorm.Config
+}
+
+// WithDialector 替换根据 DSN 创建 gorm.Dialector 的方式，默认使用 MySQL 驱动
+// 例如在本地或测试中使用 SQLite 文件代替主从库: database.WithDialector(sqlite.Open)
+func WithDialector(fn func(dsn string) gorm.Dialector) Option {
+	return func(o *options) {
+		o.dialector = fn
+	}
+}
+
+// WithGormConfig 指定打开数据库时使用的 gorm.Config (其中的 Logger 会被 Open 的 gormLogger 参数覆盖)
+func WithGormConfig(cfg *gorm.Config) Option {
+	return func(o *options) {
+		o.gormConfig = cfg
+	}
+}
+
+// Open 根据 DatabaseConfig 打开带读写分离的 GORM 连接
+// - 写操作 (Create/Update/Delete)、事务、SELECT ... FOR UPDATE 路由到主库
+// - 读操作 (Find/First/Scan/Row 以及 Raw 的 SELECT) 路由到从库，没有配置从库时走主库
+// - 使用 ForcePrimary 返回的 context 执行的读操作会路由到主库，用于写后立即读 (规避复制延迟)
+// - gormLogger 为 nil 时不输出 SQL 日志
+//
+// 使用方式:
+//
+//	db, err := database.Open(cfg.Database, core.NewGormLogger(zapLogger, cfg.GormLog))
+//	defer database.Close(db)
+func Open(cfg config.DatabaseConfig, gormLogger *core.GormLogger, opts ...Option) (*gorm.DB, error) {
+	o := &options{dialector: mysql.Open}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if cfg.PrimaryDSN == "" {
+		return nil, errors.New("数据库配置缺少 primaryDSN")
+	}
+
+	gormCfg := &gorm.Config{}
+	if o.gormConfig != nil {
+		copied := *o.gormConfig
+		gormCfg = &copied
+	}
+	if gormLogger != nil {
+		gormCfg.Logger = gormLogger
+	} else {
+		gormCfg.Logger = logger.Discard
+	}
+
+	policy, err := replicaPolicy(cfg.ReplicaPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(o.dialector(cfg.PrimaryDSN), gormCfg)
+	if err != nil {
+		return nil, fmt.Errorf("连接主库失败: %w", err)
+	}
+
+	replicas := make([]gorm.Dialector, 0, len(cfg.ReplicaDSNs))
+	for _, dsn := range cfg.ReplicaDSNs {
+		replicas = append(replicas, o.dialector(dsn))
+	}
+	// Sources 留空: dbresolver 会把上面打开的主库连接作为写库
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   policy,
+	})
+	if cfg.MaxOpenConns > 0 {
+		resolver.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		resolver.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		resolver.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		resolver.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	if err := db.Use(resolver); err != nil {
+		_ = closeResolver(db, resolver)
+		return nil, fmt.Errorf("初始化读写分离失败: %w", err)
+	}
+	if err := registerForcePrimary(db); err != nil {
+		_ = closeResolver(db, resolver)
+		return nil, fmt.Errorf("注册强制主库回调失败: %w", err)
+	}
+	return db, nil
+}
+
+// Close 关闭 Open 打开的主库和所有从库连接池
+func Close(db *gorm.DB) error {
+	if plugin, ok := db.Config.Plugins[(&dbresolver.DBResolver{}).Name()]; ok {
+		if resolver, ok := plugin.(*dbresolver.DBResolver); ok {
+			return closeResolver(db, resolver)
+		}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// closeResolver 关闭 dbresolver 管理的所有连接池 (包含作为写库的主库连接)
+func closeResolver(db *gorm.DB, resolver *dbresolver.DBResolver) error {
+	var errs []error
+	closed := make(map[gorm.ConnPool]struct{})
+	closeFn := func(connPool gorm.ConnPool) error {
+		if _, ok := closed[connPool]; ok {
+			return nil
+		}
+		closed[connPool] = struct{}{}
+		if c, ok := connPool.(interface{ Close() error }); ok {
+			errs = append(errs, c.Close())
+		}
+		return nil
+	}
+	_ = resolver.Call(closeFn)
+	if sqlDB, err := db.DB(); err == nil {
+		_ = closeFn(sqlDB)
+	}
+	return errors.Join(errs...)
+}
+
+// replicaPolicy 把配置中的策略名称转换为 dbresolver.Policy
+func replicaPolicy(name string) (dbresolver.Policy, error) {
+	switch name {
+	case "", ReplicaPolicyRandom:
+		return dbresolver.RandomPolicy{}, nil
+	case ReplicaPolicyRoundRobin:
+		return dbresolver.RoundRobinPolicy(), nil
+	default:
+		return nil, fmt.Errorf("未知的从库负载均衡策略: %s", name)
+	}
+}
+
+// forcePrimaryCtxKey 是 context 中保存强制主库标记的键
+type forcePrimaryCtxKey struct{}
+
+// ForcePrimary 返回一个标记为强制读主库的 context
+// 写入数据后需要立即读取时使用，避免从库复制延迟导致读到旧数据:
+//
+//	ctx = database.ForcePrimary(ctx)
+//	db.WithContext(ctx).Create(&post)
+//	db.WithContext(ctx).First(&post, post.ID) // 从主库读取
+//
+// 单条语句也可以直接使用 db.Clauses(dbresolver.Write)。
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryCtxKey{}, true)
+}
+
+// IsPrimaryForced 判断 context 是否被标记为强制读主库
+func IsPrimaryForced(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	forced, _ := ctx.Value(forcePrimaryCtxKey{}).(bool)
+	return forced
+}
+
+// registerForcePrimary 在读操作 (query/row/raw) 执行前检查 context，必要时切换到主库
+// dbresolver 的路由回调注册在所有回调之前 ("*")，因此这里注册在它之后，由 dbresolver.Write 重新路由
+func registerForcePrimary(db *gorm.DB) error {
+	fn := func(db *gorm.DB) {
+		if db.Statement != nil && IsPrimaryForced(db.Statement.Context) {
+			dbresolver.Write.ModifyStatement(db.Statement)
+		}
+	}
+	cb := db.Callback()
+	return errors.Join(
+		cb.Query().After("gorm:db_resolver").Before("gorm:query").Register(forcePrimaryCallbackName, fn),
+		cb.Row().After("gorm:db_resolver").Before("gorm:row").Register(forcePrimaryCallbackName, fn),
+		cb.Raw().After("gorm:db_resolver").Before("gorm:raw").Register(forcePrimaryCallbackName, fn),
+	)
+}
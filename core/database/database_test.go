@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+type post struct {
+	ID    uint `gorm:"primaryKey"`
+	Title string
+}
+
+// openFile 直接打开一个 SQLite 文件，建好 post 表并写入 titles
+func openFile(t *testing.T, path string, titles ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开 %s 失败: %v", path, err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.AutoMigrate(&post{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	for _, title := range titles {
+		if err := db.Create(&post{Title: title}).Error; err != nil {
+			t.Fatalf("写入 %s 失败: %v", path, err)
+		}
+	}
+	return db
+}
+
+// openPrimaryReplica 用两个 SQLite 文件模拟主从库: 主库有 "primary"，从库有 "replica"
+// 返回 Open 打开的读写分离连接，以及直接访问主库、从库文件的连接
+func openPrimaryReplica(t *testing.T) (db, primary, replica *gorm.DB) {
+	t.Helper()
+	dir := t.TempDir()
+	primaryPath := filepath.Join(dir, "primary.db")
+	replicaPath := filepath.Join(dir, "replica.db")
+	primary = openFile(t, primaryPath, "primary")
+	replica = openFile(t, replicaPath, "replica")
+
+	db, err := Open(config.DatabaseConfig{
+		PrimaryDSN:  primaryPath,
+		ReplicaDSNs: []string{replicaPath},
+	}, nil, WithDialector(sqlite.Open))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = Close(db) })
+	return db, primary, replica
+}
+
+// titles 返回 db 中所有 post 的标题
+func titles(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var got []string
+	if err := db.Model(&post{}).Order("id").Pluck("title", &got).Error; err != nil {
+		t.Fatalf("读取标题失败: %v", err)
+	}
+	return got
+}
+
+func TestOpenRoutesReadsToReplica(t *testing.T) {
+	db, _, _ := openPrimaryReplica(t)
+
+	var first post
+	if err := db.First(&first).Error; err != nil || first.Title != "replica" {
+		t.Errorf("First 应读从库，实际 %+v, %v", first, err)
+	}
+	var found []post
+	if err := db.Find(&found).Error; err != nil || len(found) != 1 || found[0].Title != "replica" {
+		t.Errorf("Find 应读从库，实际 %+v, %v", found, err)
+	}
+	var raw string
+	if err := db.Raw("SELECT title FROM posts LIMIT 1").Scan(&raw).Error; err != nil || raw != "replica" {
+		t.Errorf("Raw SELECT 应读从库，实际 %q, %v", raw, err)
+	}
+	var row string
+	if err := db.Model(&post{}).Select("title").Row().Scan(&row); err != nil || row != "replica" {
+		t.Errorf("Row 应读从库，实际 %q, %v", row, err)
+	}
+}
+
+func TestOpenRoutesWritesToPrimary(t *testing.T) {
+	db, primary, replica := openPrimaryReplica(t)
+
+	if err := db.Create(&post{Title: "created"}).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := db.Model(&post{}).Where("title = ?", "primary").Update("title", "updated").Error; err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := db.Exec("INSERT INTO posts (title) VALUES (?)", "exec").Error; err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+
+	if got := titles(t, primary); len(got) != 3 || got[0] != "updated" || got[1] != "created" || got[2] != "exec" {
+		t.Errorf("写操作应落在主库，主库内容 %v", got)
+	}
+	if got := titles(t, replica); len(got) != 1 || got[0] != "replica" {
+		t.Errorf("写操作不应落在从库，从库内容 %v", got)
+	}
+}
+
+func TestForcePrimary(t *testing.T) {
+	db, _, _ := openPrimaryReplica(t)
+	ctx := ForcePrimary(context.Background())
+	if !IsPrimaryForced(ctx) || IsPrimaryForced(context.Background()) {
+		t.Fatal("IsPrimaryForced 与 ForcePrimary 不一致")
+	}
+
+	if err := db.WithContext(ctx).Create(&post{Title: "fresh"}).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := titles(t, db.WithContext(ctx)); len(got) != 2 || got[1] != "fresh" {
+		t.Errorf("ForcePrimary 的读操作应读主库，实际 %v", got)
+	}
+	var raw string
+	if err := db.WithContext(ctx).Raw("SELECT title FROM posts ORDER BY id LIMIT 1").Scan(&raw).Error; err != nil || raw != "primary" {
+		t.Errorf("ForcePrimary 的 Raw SELECT 应读主库，实际 %q, %v", raw, err)
+	}
+	var row string
+	if err := db.WithContext(ctx).Model(&post{}).Select("title").Order("id").Row().Scan(&row); err != nil || row != "primary" {
+		t.Errorf("ForcePrimary 的 Row 应读主库，实际 %q, %v", row, err)
+	}
+
+	// 未标记的 context 仍然读从库
+	if got := titles(t, db); len(got) != 1 || got[0] != "replica" {
+		t.Errorf("未标记 ForcePrimary 时应读从库，实际 %v", got)
+	}
+}
+
+func TestOpenTransactionAndWriteClauseUsePrimary(t *testing.T) {
+	db, _, _ := openPrimaryReplica(t)
+
+	if got := titles(t, db.Clauses(dbresolver.Write)); len(got) != 1 || got[0] != "primary" {
+		t.Errorf("dbresolver.Write 应读主库，实际 %v", got)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if got := titles(t, tx); len(got) != 1 || got[0] != "primary" {
+			t.Errorf("事务内的读操作应读主库，实际 %v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+}
+
+func TestOpenWithoutReplicaUsesPrimary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "primary.db")
+	openFile(t, path, "primary")
+
+	db, err := Open(config.DatabaseConfig{PrimaryDSN: path}, nil, WithDialector(sqlite.Open))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer Close(db)
+	if got := titles(t, db); len(got) != 1 || got[0] != "primary" {
+		t.Errorf("没有从库时应读主库，实际 %v", got)
+	}
+}
+
+func TestOpenConfigErrors(t *testing.T) {
+	if _, err := Open(config.DatabaseConfig{}, nil, WithDialector(sqlite.Open)); err == nil {
+		t.Error("缺少 primaryDSN 时应返回错误")
+	}
+	path := filepath.Join(t.TempDir(), "primary.db")
+	if _, err := Open(config.DatabaseConfig{PrimaryDSN: path, ReplicaPolicy: "weighted"}, nil, WithDialector(sqlite.Open)); err == nil {
+		t.Error("未知的从库负载均衡策略应返回错误")
+	}
+}
+
+func TestCloseClosesAllPools(t *testing.T) {
+	db, _, _ := openPrimaryReplica(t)
+	if err := Close(db); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	var got []post
+	if err := db.Find(&got).Error; err == nil {
+		t.Error("Close 之后从库连接池应已关闭")
+	}
+	if err := db.Create(&post{Title: "x"}).Error; err == nil {
+		t.Error("Close 之后主库连接池应已关闭")
+	}
+}
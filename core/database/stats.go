@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Xushengqwer/go-common/core"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const (
+	// meterName 是连接池指标使用的 Meter 名称
+	meterName = "github.com/Xushengqwer/go-common/core/database"
+	// defaultStatsInterval 是未指定间隔时输出连接池统计日志的周期
+	defaultStatsInterval = 30 * time.Second
+	// defaultPingTimeout 是未指定超时时就绪检查 Ping 每个连接池的超时时间
+	defaultPingTimeout = 2 * time.Second
+)
+
+// 连接池指标的属性键 (参考 OTel 数据库客户端语义约定)
+const (
+	poolNameKey = attribute.Key("db.client.connection.pool.name")
+	stateKey    = attribute.Key("db.client.connection.state")
+)
+
+// namedPool 是一个注册到 StatsCollector 的连接池
+type namedPool struct {
+	name string
+	db   *sql.DB
+}
+
+// StatsCollector 定期记录并以 OTel 指标导出已注册连接池的 sql.DBStats
+// 连接池耗尽时请求表现为普通的超时，有了等待次数和等待时长才能定位到数据库连接不足。
+// - 日志: 每个周期为每个连接池输出一条 "DB pool stats"
+// - 指标: db.client.connection.count (state=used/idle)、db.client.connection.max、wait.count、wait.duration (秒)
+// - 就绪检查: Ready 在超时时间内 Ping 每个连接池 (HTTP 处理器见 middleware.DBReadinessHandler)
+type StatsCollector struct {
+	zapLogger    *core.ZapLogger
+	interval     time.Duration
+	pingTimeout  time.Duration
+	registration metric.Registration
+
+	mu    sync.RWMutex
+	pools []namedPool
+
+	stopOnce sync.Once
+	stopErr  error // 第一次 Stop 注销指标回调的结果
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// StatsOption 定义 NewStatsCollector 的可选配置项
+type StatsOption func(*statsOptions)
+
+// statsOptions 保存 NewStatsCollector 的可选配置
+type statsOptions struct {
+	interval      time.Duration
+	pingTimeout   time.Duration
+	meterProvider metric.MeterProvider
+}
+
+// WithStatsInterval 设置输出连接池统计日志的周期，默认 30s
+func WithStatsInterval(interval time.Duration) StatsOption {
+	return func(o *statsOptions) {
+		o.interval = interval
+	}
+}
+
+// WithPingTimeout 设置就绪检查 Ping 每个连接池的超时时间，默认 2s
+func WithPingTimeout(timeout time.Duration) StatsOption {
+	return func(o *statsOptions) {
+		o.pingTimeout = timeout
+	}
+}
+
+// WithMeterProvider 指定导出指标使用的 MeterProvider，默认使用全局的 otel.GetMeterProvider()
+func WithMeterProvider(mp metric.MeterProvider) StatsOption {
+	return func(o *statsOptions) {
+		o.meterProvider = mp
+	}
+}
+
+// NewStatsCollector 创建连接池统计收集器，注册 OTel 指标并启动定期输出日志的后台协程
+// 不再使用时调用 Stop 结束后台协程并注销指标回调
+func NewStatsCollector(zapLogger *core.ZapLogger, opts ...StatsOption) (*StatsCollector, error) {
+	o := &statsOptions{interval: defaultStatsInterval, pingTimeout: defaultPingTimeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.interval <= 0 {
+		o.interval = defaultStatsInterval
+	}
+	if o.pingTimeout <= 0 {
+		o.pingTimeout = defaultPingTimeout
+	}
+	if o.meterProvider == nil {
+		o.meterProvider = otel.GetMeterProvider()
+	}
+
+	c := &StatsCollector{
+		zapLogger:   zapLogger,
+		interval:    o.interval,
+		pingTimeout: o.pingTimeout,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if err := c.registerMetrics(o.meterProvider.Meter(meterName)); err != nil {
+		return nil, fmt.Errorf("注册连接池指标失败: %w", err)
+	}
+	go c.run()
+	return c, nil
+}
+
+// Register 注册一个 *gorm.DB 的所有连接池
+// 通过 Open 打开的读写分离连接会同时注册主库 (<name>) 和每个从库 (<name>-replica-<i>)
+func (c *StatsCollector) Register(name string, db *gorm.DB) error {
+	pools, err := gormPools(name, db)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools = append(c.pools, pools...)
+	return nil
+}
+
+// gormPools 返回 *gorm.DB 背后的所有 *sql.DB
+func gormPools(name string, db *gorm.DB) ([]namedPool, error) {
+	primary, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("获取连接池 %s 失败: %w", name, err)
+	}
+	pools := []namedPool{{name: name, db: primary}}
+
+	plugin, ok := db.Config.Plugins[(&dbresolver.DBResolver{}).Name()]
+	if !ok {
+		return pools, nil
+	}
+	resolver, ok := plugin.(*dbresolver.DBResolver)
+	if !ok {
+		return pools, nil
+	}
+	seen := map[*sql.DB]struct{}{primary: {}}
+	_ = resolver.Call(func(connPool gorm.ConnPool) error {
+		sqlDB, ok := connPool.(*sql.DB)
+		if !ok {
+			return nil
+		}
+		if _, dup := seen[sqlDB]; dup {
+			return nil
+		}
+		seen[sqlDB] = struct{}{}
+		pools = append(pools, namedPool{name: fmt.Sprintf("%s-replica-%d", name, len(pools)-1), db: sqlDB})
+		return nil
+	})
+	return pools, nil
+}
+
+// snapshot 返回当前已注册连接池的副本
+func (c *StatsCollector) snapshot() []namedPool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]namedPool(nil), c.pools...)
+}
+
+// registerMetrics 创建异步指标，采集时读取每个连接池的 sql.DBStats
+func (c *StatsCollector) registerMetrics(meter metric.Meter) error {
+	connCount, err := meter.Int64ObservableUpDownCounter("db.client.connection.count",
+		metric.WithDescription("连接池中处于各状态的连接数"), metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	connMax, err := meter.Int64ObservableUpDownCounter("db.client.connection.max",
+		metric.WithDescription("连接池允许的最大打开连接数 (0 表示不限制)"), metric.WithUnit("{connection}"))
+	if err != nil {
+		return err
+	}
+	waitCount, err := meter.Int64ObservableCounter("db.client.connection.wait.count",
+		metric.WithDescription("因连接池耗尽而等待连接的累计次数"), metric.WithUnit("{wait}"))
+	if err != nil {
+		return err
+	}
+	waitDuration, err := meter.Float64ObservableCounter("db.client.connection.wait.duration",
+		metric.WithDescription("等待连接的累计时长"), metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	c.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		for _, p := range c.snapshot() {
+			s := p.db.Stats()
+			pool := metric.WithAttributes(poolNameKey.String(p.name))
+			o.ObserveInt64(connCount, int64(s.InUse), metric.WithAttributes(poolNameKey.String(p.name), stateKey.String("used")))
+			o.ObserveInt64(connCount, int64(s.Idle), metric.WithAttributes(poolNameKey.String(p.name), stateKey.String("idle")))
+			o.ObserveInt64(connMax, int64(s.MaxOpenConnections), pool)
+			o.ObserveInt64(waitCount, s.WaitCount, pool)
+			o.ObserveFloat64(waitDuration, s.WaitDuration.Seconds(), pool)
+		}
+		return nil
+	}, connCount, connMax, waitCount, waitDuration)
+	return err
+}
+
+// run 定期输出连接池统计日志，直到 Stop 被调用
+func (c *StatsCollector) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.logStats()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// logStats 为每个连接池输出一条统计日志
+func (c *StatsCollector) logStats() {
+	for _, p := range c.snapshot() {
+		s := p.db.Stats()
+		c.zapLogger.Info("DB pool stats",
+			zap.String("db.client.connection.pool.name", p.name),
+			zap.Int("max_open", s.MaxOpenConnections),
+			zap.Int("open", s.OpenConnections),
+			zap.Int("in_use", s.InUse),
+			zap.Int("idle", s.Idle),
+			zap.Int64("wait_count", s.WaitCount),
+			zap.Float64("wait_duration_ms", float64(s.WaitDuration)/float64(time.Millisecond)),
+			zap.Int64("max_idle_closed", s.MaxIdleClosed),
+			zap.Int64("max_lifetime_closed", s.MaxLifetimeClosed),
+		)
+	}
+}
+
+// Ready 在超时时间内并发 Ping 每个已注册的连接池，返回所有失败连接池的错误
+func (c *StatsCollector) Ready(ctx context.Context) error {
+	pools := c.snapshot()
+	errs := make([]error, len(pools))
+	var wg sync.WaitGroup
+	for i, p := range pools {
+		wg.Add(1)
+		go func(i int, p namedPool) {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, c.pingTimeout)
+			defer cancel()
+			if err := p.db.PingContext(pingCtx); err != nil {
+				errs[i] = fmt.Errorf("连接池 %s 不可用: %w", p.name, err)
+			}
+		}(i, p)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Stop 停止后台协程并注销指标回调
+// 可以重复调用: 只有第一次调用会注销回调，之后的调用返回同一个结果
+func (c *StatsCollector) Stop() error {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+		<-c.done
+		c.stopErr = c.registration.Unregister()
+	})
+	return c.stopErr
+}
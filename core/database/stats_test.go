@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// countingMeterProvider 返回的 Meter 记录指标回调被注销的次数
+type countingMeterProvider struct {
+	noop.MeterProvider
+	meter *countingMeter
+}
+
+func (p countingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter { return p.meter }
+
+type countingMeter struct {
+	noop.Meter
+	mu          sync.Mutex
+	unregisters int
+	err         error // Unregister 返回的错误
+}
+
+func (m *countingMeter) RegisterCallback(metric.Callback, ...metric.Observable) (metric.Registration, error) {
+	return countingRegistration{m: m}, nil
+}
+
+type countingRegistration struct {
+	noop.Registration
+	m *countingMeter
+}
+
+func (r countingRegistration) Unregister() error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.m.unregisters++
+	return r.m.err
+}
+
+func TestStatsCollectorStopUnregistersOnce(t *testing.T) {
+	unregisterErr := errors.New("unregister failed")
+	meter := &countingMeter{err: unregisterErr}
+	logger, err := core.NewZapLogger(config.ZapConfig{Level: "error"})
+	if err != nil {
+		t.Fatalf("NewZapLogger: %v", err)
+	}
+	c, err := NewStatsCollector(logger, WithMeterProvider(countingMeterProvider{meter: meter}))
+	if err != nil {
+		t.Fatalf("NewStatsCollector: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c.Stop()
+		}(i)
+	}
+	wg.Wait()
+
+	if meter.unregisters != 1 {
+		t.Errorf("指标回调应只注销一次，实际 %d 次", meter.unregisters)
+	}
+	for i, err := range errs {
+		if !errors.Is(err, unregisterErr) {
+			t.Errorf("第 %d 次 Stop 应返回第一次注销的错误，实际 %v", i, err)
+		}
+	}
+}
+
+// newTestCollector 返回使用 noop MeterProvider 的收集器，测试结束时停止
+func newTestCollector(t *testing.T) *StatsCollector {
+	t.Helper()
+	logger, err := core.NewZapLogger(config.ZapConfig{Level: "error"})
+	if err != nil {
+		t.Fatalf("NewZapLogger: %v", err)
+	}
+	c, err := NewStatsCollector(logger, WithMeterProvider(noop.NewMeterProvider()))
+	if err != nil {
+		t.Fatalf("NewStatsCollector: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Stop() })
+	return c
+}
+
+// TestStatsCollectorRegisterResolver 读写分离连接同时注册主库和每个从库的连接池
+func TestStatsCollectorRegisterResolver(t *testing.T) {
+	db, _, _ := openPrimaryReplica(t)
+	c := newTestCollector(t)
+	if err := c.Register("main", db); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	pools := c.snapshot()
+	var names []string
+	for _, p := range pools {
+		names = append(names, p.name)
+	}
+	if len(pools) != 2 || names[0] != "main" || names[1] != "main-replica-0" {
+		t.Fatalf("应注册主库和从库的连接池，实际 %v", names)
+	}
+	if pools[0].db == pools[1].db {
+		t.Error("主库和从库应是不同的连接池")
+	}
+	if err := c.Ready(context.Background()); err != nil {
+		t.Errorf("所有连接池可用时 Ready 应返回 nil，实际 %v", err)
+	}
+}
+
+// TestStatsCollectorReadyClosedPool 已关闭的连接池让 Ready 返回包含连接池名称的错误
+func TestStatsCollectorReadyClosedPool(t *testing.T) {
+	db, _, _ := openPrimaryReplica(t)
+	c := newTestCollector(t)
+	if err := c.Register("main", db); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := c.snapshot()[1].db.Close(); err != nil {
+		t.Fatalf("关闭从库连接池失败: %v", err)
+	}
+
+	err := c.Ready(context.Background())
+	if err == nil {
+		t.Fatal("连接池已关闭时 Ready 应返回错误")
+	}
+	if !strings.Contains(err.Error(), "main-replica-0") || strings.Contains(err.Error(), "连接池 main 不可用") {
+		t.Errorf("错误应只指出已关闭的从库连接池，实际 %v", err)
+	}
+}
+
+// TestStatsCollectorStopIdempotent 重复调用 Stop 不会 panic，后台协程已结束
+func TestStatsCollectorStopIdempotent(t *testing.T) {
+	c := newTestCollector(t)
+	for i := 0; i < 3; i++ {
+		if err := c.Stop(); err != nil {
+			t.Fatalf("第 %d 次 Stop 返回错误: %v", i, err)
+		}
+	}
+	select {
+	case <-c.done:
+	default:
+		t.Error("Stop 返回后后台协程应已结束")
+	}
+}
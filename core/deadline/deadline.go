@@ -0,0 +1,98 @@
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header 是跨服务传递请求截止时间的请求头，取值为截止时间的 Unix 毫秒时间戳
+// 使用绝对时间而不是剩余时长，转发时不需要再扣除排队和网络耗时。
+//
+// 前提: 所有服务的时钟已通过 NTP 等方式同步。出站时的 SafetyMargin 只能吸收小于它的时钟偏差:
+// 下游时钟比上游快时截止时间提前到来 (请求被过早地判定超时，偏差足够大时直接返回 504)，
+// 下游时钟比上游慢时下游会在上游放弃之后继续处理。时钟偏差可能超过余量的部署应加大 SafetyMargin，或不使用该请求头。
+const Header = "X-Request-Deadline"
+
+// DefaultSafetyMargin 是出站请求写入截止时间时默认预留的余量
+// 下游需要在上游放弃之前返回结果，余量覆盖网络往返和服务间的时钟偏差 (假设偏差在几十毫秒以内)。
+const DefaultSafetyMargin = 50 * time.Millisecond
+
+// Format 把截止时间格式化为请求头的取值
+func Format(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
+
+// Parse 解析请求头中的截止时间，取值为空或格式无效时第二个返回值为 false
+func Parse(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// FromRequest 返回入站请求携带的截止时间，没有携带或格式无效时第二个返回值为 false
+func FromRequest(r *http.Request) (time.Time, bool) {
+	return Parse(r.Header.Get(Header))
+}
+
+// Transport 是一个 http.RoundTripper，根据请求 context 的截止时间为出站请求写入 X-Request-Deadline
+// - 写入的截止时间为 ctx.Deadline() 减去 SafetyMargin，下游会在上游放弃之前结束处理
+// - 扣除余量后已经没有剩余时间的请求不会发出，直接返回包装了 context.DeadlineExceeded 的错误
+// - context 没有截止时间时原样转发，请求中已有的 X-Request-Deadline 不会被修改
+type Transport struct {
+	// Base 是实际发送请求的 RoundTripper，为 nil 时使用 http.DefaultTransport
+	Base http.RoundTripper
+	// SafetyMargin 是从截止时间中扣除的余量，<= 0 时使用 DefaultSafetyMargin
+	SafetyMargin time.Duration
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// NewTransport 创建截止时间传播的 RoundTripper，例如:
+//
+//	client := &http.Client{Transport: deadline.NewTransport(nil, 0)}
+//	req, _ := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, url, nil)
+func NewTransport(base http.RoundTripper, margin time.Duration) *Transport {
+	return &Transport{Base: base, SafetyMargin: margin}
+}
+
+// RoundTrip 实现 http.RoundTripper 接口
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	d, ok := req.Context().Deadline()
+	if !ok || req.Header.Get(Header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	margin := t.SafetyMargin
+	if margin <= 0 {
+		margin = DefaultSafetyMargin
+	}
+	d = d.Add(-margin)
+	if !time.Now().Before(d) {
+		closeBody(req)
+		return nil, fmt.Errorf("请求截止时间已不足 %s，不再发起调用 %s %s: %w", margin, req.Method, req.URL.Redacted(), context.DeadlineExceeded)
+	}
+
+	// RoundTripper 不能修改调用方的请求，复制后再写入请求头
+	out := req.Clone(req.Context())
+	out.Header.Set(Header, Format(d))
+	return base.RoundTrip(out)
+}
+
+// closeBody 关闭未发出的请求的 Body，RoundTripper 即使返回错误也必须关闭请求体
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSlowQueryReportInterval 是未配置 SlowQueryReportIntervalSec 时的汇总间隔
+	defaultSlowQueryReportInterval = 60 * time.Second
+	// maxSlowQueryFingerprints 是一个汇总周期内最多跟踪的语句指纹数量，超出的新指纹只计入 Dropped
+	maxSlowQueryFingerprints = 1000
+	// maxSlowQuerySamples 是每个指纹保留的耗时样本数量 (蓄水池抽样)，用于计算 p50/p95
+	maxSlowQuerySamples = 1024
+)
+
+var (
+	fingerprintStringRe  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"`)
+	fingerprintNumberRe  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b|\$\d+`)
+	fingerprintListRe    = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintRowsRe    = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	fingerprintSpaceRe   = regexp.MustCompile(`\s+`)
+	fingerprintReplacers = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{fingerprintStringRe, "?"},
+		{fingerprintNumberRe, "?"},
+		{fingerprintListRe, "(?)"},
+		{fingerprintRowsRe, "(?)"},
+		{fingerprintSpaceRe, " "},
+	}
+)
+
+// FingerprintSQL 把 SQL 归一化为语句指纹: 字符串和数字字面量替换为 "?"，IN 列表与多行 VALUES 折叠，空白合并
+// 例如 "SELECT * FROM users WHERE id IN (1,2,3) AND name = 'bob'" -> "SELECT * FROM users WHERE id IN (?) AND name = ?"
+func FingerprintSQL(sql string) string {
+	for _, r := range fingerprintReplacers {
+		sql = r.re.ReplaceAllString(sql, r.repl)
+	}
+	return strings.TrimSpace(sql)
+}
+
+// SlowQueryStat 是一个语句指纹在汇总周期内的慢查询统计
+type SlowQueryStat struct {
+	Fingerprint string  `json:"fingerprint"`
+	Count       int64   `json:"count"`
+	P50Ms       float64 `json:"p50_ms"`
+	P95Ms       float64 `json:"p95_ms"`
+	MaxMs       float64 `json:"max_ms"`
+	TotalRows   int64   `json:"total_rows"`
+	SampleSQL   string  `json:"sample_sql"` // 最近一次命中的语句 (已经过脱敏与截断)
+}
+
+// SlowQueryReport 是一个汇总周期的慢查询报告
+type SlowQueryReport struct {
+	WindowStart time.Time       `json:"window_start"`
+	WindowEnd   time.Time       `json:"window_end"`
+	Dropped     int64           `json:"dropped"` // 因指纹数量超过上限而未被跟踪的慢查询次数
+	Queries     []SlowQueryStat `json:"queries"` // 按次数从高到低排序
+}
+
+// slowQueryBucket 是单个指纹的累计数据
+type slowQueryBucket struct {
+	count     int64
+	totalRows int64
+	max       time.Duration
+	samples   []time.Duration
+	sampleSQL string
+}
+
+// SlowQueryAggregator 按语句指纹聚合慢查询，并定期输出汇总日志
+// 数据库故障期间逐条输出慢查询会淹没真正的信号，聚合后每个周期每个指纹只输出一条汇总。
+//
+// 生命周期: 创建时不启动协程，第一次 Record 时才启动定期汇总的后台协程；
+// 调用 Stop 结束协程并输出最后一个周期的汇总，之后的 Record 会被忽略。从未记录过慢查询时 Stop 直接返回。
+type SlowQueryAggregator struct {
+	zapLogger *ZapLogger
+	interval  time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	buckets     map[string]*slowQueryBucket
+	dropped     int64
+	previous    *SlowQueryReport
+	started     bool // 后台协程已启动
+	stopped     bool // Stop 已调用
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewSlowQueryAggregator 创建慢查询聚合器，每 interval 输出一次汇总 (后台协程在第一次 Record 时启动)
+// interval <= 0 时使用默认值 60s；不再使用时调用 Stop 结束后台协程
+func NewSlowQueryAggregator(zapLogger *ZapLogger, interval time.Duration) *SlowQueryAggregator {
+	if interval <= 0 {
+		interval = defaultSlowQueryReportInterval
+	}
+	a := &SlowQueryAggregator{
+		zapLogger:   zapLogger,
+		interval:    interval,
+		windowStart: time.Now(),
+		buckets:     make(map[string]*slowQueryBucket),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	return a
+}
+
+// Record 记录一次慢查询
+// - sql: 用于计算指纹的完整语句
+// - sampleSQL: 报告中展示的语句 (调用方负责脱敏与截断)
+// Stop 之后的调用会被忽略
+func (a *SlowQueryAggregator) Record(sql, sampleSQL string, elapsed time.Duration, rows int64) {
+	fingerprint := FingerprintSQL(sql)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return
+	}
+	if !a.started {
+		a.started = true
+		a.windowStart = time.Now()
+		go a.run()
+	}
+
+	b, ok := a.buckets[fingerprint]
+	if !ok {
+		if len(a.buckets) >= maxSlowQueryFingerprints {
+			a.dropped++
+			return
+		}
+		b = &slowQueryBucket{}
+		a.buckets[fingerprint] = b
+	}
+	b.count++
+	if rows > 0 {
+		b.totalRows += rows
+	}
+	if elapsed > b.max {
+		b.max = elapsed
+	}
+	b.sampleSQL = sampleSQL
+	// 蓄水池抽样，保证样本数量有上限且每次慢查询被保留的概率相同
+	if len(b.samples) < maxSlowQuerySamples {
+		b.samples = append(b.samples, elapsed)
+	} else if i := rand.Int64N(b.count); i < maxSlowQuerySamples {
+		b.samples[i] = elapsed
+	}
+}
+
+// Snapshot 返回当前汇总周期 (尚未输出) 的统计
+func (a *SlowQueryAggregator) Snapshot() SlowQueryReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.reportLocked(time.Now())
+}
+
+// Previous 返回上一个已输出的汇总报告，还没有输出过时返回 nil
+func (a *SlowQueryAggregator) Previous() *SlowQueryReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.previous
+}
+
+// Stop 停止后台汇总协程，并在返回前输出最后一个周期的汇总；可以重复调用
+func (a *SlowQueryAggregator) Stop() {
+	a.mu.Lock()
+	a.stopped = true
+	started := a.started
+	a.mu.Unlock()
+
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+	if started {
+		<-a.done
+	}
+}
+
+// run 定期输出汇总，直到 Stop 被调用
+func (a *SlowQueryAggregator) run() {
+	defer close(a.done)
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.flush()
+		case <-a.stop:
+			a.flush()
+			return
+		}
+	}
+}
+
+// flush 结束当前周期：生成报告、重置计数，并为每个指纹输出一条汇总日志
+func (a *SlowQueryAggregator) flush() {
+	now := time.Now()
+	a.mu.Lock()
+	report := a.reportLocked(now)
+	a.previous = &report
+	a.windowStart = now
+	a.buckets = make(map[string]*slowQueryBucket)
+	a.dropped = 0
+	a.mu.Unlock()
+
+	if len(report.Queries) == 0 && report.Dropped == 0 {
+		return
+	}
+	for _, q := range report.Queries {
+		a.zapLogger.Warn("SQL slow query summary",
+			zap.String("db.query.fingerprint", q.Fingerprint),
+			zap.Int64("count", q.Count),
+			zap.Float64("p50_ms", q.P50Ms),
+			zap.Float64("p95_ms", q.P95Ms),
+			zap.Float64("max_ms", q.MaxMs),
+			zap.Int64("db.rows_affected", q.TotalRows),
+			zap.String("db.statement", q.SampleSQL),
+			zap.Time("window_start", report.WindowStart),
+			zap.Duration("window", report.WindowEnd.Sub(report.WindowStart)),
+		)
+	}
+	if report.Dropped > 0 {
+		a.zapLogger.Warn("SQL slow query summary truncated",
+			zap.Int64("dropped", report.Dropped),
+			zap.Int("max_fingerprints", maxSlowQueryFingerprints),
+		)
+	}
+}
+
+// reportLocked 根据当前累计数据生成报告，调用方必须持有 a.mu
+func (a *SlowQueryAggregator) reportLocked(now time.Time) SlowQueryReport {
+	report := SlowQueryReport{
+		WindowStart: a.windowStart,
+		WindowEnd:   now,
+		Dropped:     a.dropped,
+		Queries:     make([]SlowQueryStat, 0, len(a.buckets)),
+	}
+	for fingerprint, b := range a.buckets {
+		samples := append([]time.Duration(nil), b.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		report.Queries = append(report.Queries, SlowQueryStat{
+			Fingerprint: fingerprint,
+			Count:       b.count,
+			P50Ms:       durationMs(percentile(samples, 0.50)),
+			P95Ms:       durationMs(percentile(samples, 0.95)),
+			MaxMs:       durationMs(b.max),
+			TotalRows:   b.totalRows,
+			SampleSQL:   b.sampleSQL,
+		})
+	}
+	sort.Slice(report.Queries, func(i, j int) bool {
+		return report.Queries[i].Count > report.Queries[j].Count
+	})
+	return report
+}
+
+// percentile 返回已排序样本的 p 分位数 (最近秩法)
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
+// durationMs 把时长转换为毫秒数值
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
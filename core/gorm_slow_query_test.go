@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestFingerprintSQL(t *testing.T) {
+	got := FingerprintSQL("SELECT * FROM users WHERE id IN (1, 2,3) AND name = 'bob'\n  AND phone = \"135\"")
+	if want := "SELECT * FROM users WHERE id IN (?) AND name = ? AND phone = ?"; got != want {
+		t.Errorf("FingerprintSQL = %q，期望 %q", got, want)
+	}
+}
+
+func TestSlowQueryAggregatorStartsLazily(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	a := NewSlowQueryAggregator(&ZapLogger{logger: zap.New(core)}, time.Hour)
+	if a.started {
+		t.Fatal("创建聚合器时不应启动后台协程")
+	}
+
+	a.Record("SELECT * FROM users WHERE id = 1", "SELECT * FROM users WHERE id = 1", time.Second, 1)
+	a.Record("SELECT * FROM users WHERE id = 2", "SELECT * FROM users WHERE id = 2", 3*time.Second, 1)
+	if !a.started {
+		t.Fatal("第一次 Record 后应启动后台协程")
+	}
+	a.Stop()
+	a.Stop() // 可以重复调用
+
+	entries := logs.FilterMessage("SQL slow query summary").All()
+	if len(entries) != 1 {
+		t.Fatalf("Stop 应输出最后一个周期的汇总，实际 %d 条", len(entries))
+	}
+	if fields := entries[0].ContextMap(); fields["count"] != int64(2) || fields["max_ms"] != float64(3000) {
+		t.Errorf("汇总字段 = %v", fields)
+	}
+
+	a.Record("SELECT 1", "SELECT 1", time.Second, 0)
+	if n := len(a.Snapshot().Queries); n != 0 {
+		t.Errorf("Stop 之后的 Record 应被忽略，实际 %d 个指纹", n)
+	}
+}
+
+func TestSlowQueryAggregatorStopWithoutRecord(t *testing.T) {
+	a := NewSlowQueryAggregator(&ZapLogger{logger: zap.NewNop()}, time.Hour)
+	done := make(chan struct{})
+	go func() {
+		a.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("从未记录过慢查询时 Stop 不应阻塞")
+	}
+}
+
+func TestGormLoggerAggregatesAtSilentLevel(t *testing.T) {
+	g := NewGormLogger(&ZapLogger{logger: zap.NewNop()}, config.GormLogConfig{
+		Level:                "silent",
+		SlowThresholdMs:      1,
+		SlowQueryAggregation: true,
+		SkipCallerLookup:     true,
+	})
+	defer g.Close()
+	if g.SlowQueryAggregator().started {
+		t.Fatal("NewGormLogger 不应启动后台协程")
+	}
+
+	// 模拟两次慢查询: 实例级别为 Silent，以及 LogMode 返回的 Silent 副本
+	begin := time.Now().Add(-time.Second)
+	g.Trace(context.Background(), begin, func() (string, int64) {
+		return "SELECT * FROM traced_users WHERE id = 1", 1
+	}, nil)
+	g.LogMode(gormlogger.Silent).Trace(context.Background(), begin, func() (string, int64) {
+		return "SELECT * FROM traced_users WHERE id = 2", 1
+	}, nil)
+
+	report := g.SlowQueryAggregator().Snapshot()
+	if len(report.Queries) != 1 || report.Queries[0].Count != 2 {
+		t.Errorf("Silent 级别的慢查询也应计入汇总，实际 %+v", report.Queries)
+	}
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormTracerName 是 GORM 追踪插件创建 Tracer 时使用的名称
+const gormTracerName = "github.com/Xushengqwer/go-common/core/gorm"
+
+// 插件在 gorm.Statement 实例上保存状态使用的键名
+const (
+	gormSpanInstanceKey      = "go-common:tracing:span"
+	gormParentCtxInstanceKey = "go-common:tracing:parent_ctx"
+	gormStartInstanceKey     = "go-common:tracing:start"
+)
+
+// GORM Span 上使用的属性键 (遵循 OTel 数据库语义约定)
+const (
+	dbSystemKey       = attribute.Key("db.system")
+	dbStatementKey    = attribute.Key("db.statement")
+	dbOperationKey    = attribute.Key("db.operation")
+	dbTableKey        = attribute.Key("db.sql.table")
+	dbRowsAffectedKey = attribute.Key("db.rows_affected")
+	dbSlowQueryKey    = attribute.Key("db.slow_query")
+)
+
+var _ gorm.Plugin = (*GormTracingPlugin)(nil)
+
+// GormTracingPlugin 是一个 GORM 插件，为每次数据库操作创建一个子 Span
+// GormLogger 只会把 trace_id 写进日志，而这个插件让数据库耗时本身出现在链路中。
+// - 在 create/query/update/delete/row/raw 六类回调前后注册钩子
+// - Span 上记录 db.system、db.statement、表名、影响行数以及错误状态
+// - 超过 SlowThresholdMs 的查询会被标记为慢查询 (db.slow_query=true)
+type GormTracingPlugin struct {
+	tracer                    trace.Tracer
+	slowThreshold             time.Duration
+	redactParams              bool
+	ignoreRecordNotFoundError bool
+	redactor                  *sqlRedactor
+}
+
+// NewGormTracingPlugin 根据共享的 GormLogConfig 创建 GORM 追踪插件
+// - cfg.SlowThresholdMs: 慢查询阈值，<= 0 时使用与 GormLogger 相同的默认值 200ms
+// - cfg.TraceRedactParams: 为 true 时 db.statement 只记录带占位符的 SQL，不包含参数值
+// - cfg.IgnoreRecordNotFoundError: 为 true 时 'record not found' 不会把 Span 标记为错误
+// - cfg.RedactColumns / cfg.MaxStatementLength: 与 GormLogger 相同的按列脱敏和语句截断
+//
+// 使用方式: db.Use(core.NewGormTracingPlugin(cfg.GormLog))
+func NewGormTracingPlugin(cfg config.GormLogConfig) *GormTracingPlugin {
+	slowThreshold := time.Duration(cfg.SlowThresholdMs) * time.Millisecond
+	if slowThreshold <= 0 {
+		slowThreshold = 200 * time.Millisecond // 与 NewGormLogger 的默认值保持一致
+	}
+
+	return &GormTracingPlugin{
+		// 使用全局 TracerProvider 的代理 Tracer，即使插件先于 InitTracerProvider 注册也能正常工作
+		tracer:                    otel.Tracer(gormTracerName),
+		slowThreshold:             slowThreshold,
+		redactParams:              cfg.TraceRedactParams,
+		ignoreRecordNotFoundError: cfg.IgnoreRecordNotFoundError,
+		redactor:                  newSQLRedactor(cfg),
+	}
+}
+
+// Name 实现 gorm.Plugin 接口
+func (p *GormTracingPlugin) Name() string {
+	return "go-common:tracing"
+}
+
+// Initialize 实现 gorm.Plugin 接口，注册所有的追踪回调
+// before 钩子注册在所有回调之前 ("*")，after 钩子注册在所有回调之后，保证 Span 覆盖完整的执行过程
+func (p *GormTracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("go-common:tracing:before_create", p.before("create")),
+		cb.Create().After("*").Register("go-common:tracing:after_create", p.after("create")),
+		cb.Query().Before("*").Register("go-common:tracing:before_query", p.before("query")),
+		cb.Query().After("*").Register("go-common:tracing:after_query", p.after("query")),
+		cb.Update().Before("*").Register("go-common:tracing:before_update", p.before("update")),
+		cb.Update().After("*").Register("go-common:tracing:after_update", p.after("update")),
+		cb.Delete().Before("*").Register("go-common:tracing:before_delete", p.before("delete")),
+		cb.Delete().After("*").Register("go-common:tracing:after_delete", p.after("delete")),
+		cb.Row().Before("*").Register("go-common:tracing:before_row", p.before("row")),
+		cb.Row().After("*").Register("go-common:tracing:after_row", p.after("row")),
+		cb.Raw().Before("*").Register("go-common:tracing:before_raw", p.before("raw")),
+		cb.Raw().After("*").Register("go-common:tracing:after_raw", p.after("raw")),
+	)
+}
+
+// before 返回在操作执行前启动 Span 的回调
+// 新的 Span 会写回 Statement.Context，执行期间的其他回调都能看到它；after 中会还原调用方的上下文
+func (p *GormTracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil {
+			return
+		}
+		parentCtx := db.Statement.Context
+		if parentCtx == nil {
+			parentCtx = context.Background()
+		}
+
+		ctx, span := p.tracer.Start(parentCtx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient))
+
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanInstanceKey, span)
+		db.InstanceSet(gormParentCtxInstanceKey, parentCtx)
+		db.InstanceSet(gormStartInstanceKey, time.Now())
+	}
+}
+
+// after 返回在操作执行后补充属性并结束 Span 的回调
+func (p *GormTracingPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil {
+			return
+		}
+		val, ok := db.InstanceGet(gormSpanInstanceKey)
+		if !ok {
+			return
+		}
+		span, ok := val.(trace.Span)
+		if !ok {
+			return
+		}
+		defer span.End()
+
+		// 还原调用方的上下文，避免同一个 Statement 后续的操作挂在已结束的 Span 下
+		if parentCtx, ok := db.InstanceGet(gormParentCtxInstanceKey); ok {
+			if ctx, ok := parentCtx.(context.Context); ok {
+				db.Statement.Context = ctx
+			}
+		}
+
+		attrs := []attribute.KeyValue{
+			dbSystemKey.String(db.Dialector.Name()),
+			dbOperationKey.String(operation),
+			dbStatementKey.String(p.statement(db)),
+			dbRowsAffectedKey.Int64(db.RowsAffected),
+		}
+		if db.Statement.Table != "" {
+			attrs = append(attrs, dbTableKey.String(db.Statement.Table))
+		}
+
+		if start, ok := db.InstanceGet(gormStartInstanceKey); ok {
+			if begin, ok := start.(time.Time); ok && time.Since(begin) >= p.slowThreshold {
+				attrs = append(attrs, dbSlowQueryKey.Bool(true))
+			}
+		}
+		span.SetAttributes(attrs...)
+
+		// 记录错误状态 (可配置忽略 'record not found')
+		if err := db.Error; err != nil && !(p.ignoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
+
+// statement 返回写入 db.statement 的 SQL 文本
+// - redactParams 为 true 时返回带占位符的 SQL，参数值不会进入链路数据
+// - 否则使用方言的 Explain 生成插值后的完整 SQL，RedactColumns 中配置的列会被替换为 "***"
+// - 超过 MaxStatementLength 的语句会被截断
+func (p *GormTracingPlugin) statement(db *gorm.DB) string {
+	sql := db.Statement.SQL.String()
+	if !p.redactParams && sql != "" {
+		sql = db.Dialector.Explain(sql, p.redactor.redactVars(sql, db.Statement.Vars)...)
+	}
+	return p.redactor.truncate(sql)
+}
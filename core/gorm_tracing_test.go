@@ -0,0 +1,222 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type tracedUser struct {
+	ID    uint `gorm:"primaryKey"`
+	Name  string
+	Phone string
+}
+
+// openTestDB 打开一个独立的内存 SQLite 数据库并建好 tracedUser 表
+func openTestDB(t *testing.T, cfg *gorm.Config) *gorm.DB {
+	t.Helper()
+	if cfg == nil {
+		cfg = &gorm.Config{}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = gormlogger.Discard
+	}
+	// 每个测试使用独立的命名内存库，单连接保证建表和查询落在同一个库上
+	dsn := "file:" + strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()) + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), cfg)
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("获取 sql.DB 失败: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.AutoMigrate(&tracedUser{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return db
+}
+
+// newTracedDB 返回注册了追踪插件的数据库和记录 Span 的 SpanRecorder
+func newTracedDB(t *testing.T, cfg config.GormLogConfig) (*gorm.DB, *tracetest.SpanRecorder, *GormTracingPlugin) {
+	t.Helper()
+	db := openTestDB(t, nil)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	plugin := NewGormTracingPlugin(cfg)
+	plugin.tracer = tp.Tracer(gormTracerName)
+	if err := db.Use(plugin); err != nil {
+		t.Fatalf("注册追踪插件失败: %v", err)
+	}
+	return db, recorder, plugin
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestGormTracingPluginSpans(t *testing.T) {
+	db, recorder, _ := newTracedDB(t, config.GormLogConfig{})
+
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+	db = db.WithContext(ctx)
+
+	if err := db.Create(&tracedUser{Name: "alice", Phone: "13500000000"}).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	var got tracedUser
+	if err := db.Where("name = ?", "alice").First(&got).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if err := db.Model(&got).Update("name", "bob").Error; err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := db.Delete(&got).Error; err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	var n int
+	if err := db.Raw("SELECT COUNT(*) FROM traced_users").Row().Scan(&n); err != nil {
+		t.Fatalf("Row: %v", err)
+	}
+	if err := db.Exec("DELETE FROM traced_users WHERE id = ?", 42).Error; err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+
+	spans := recorder.Ended()
+	wantNames := []string{"gorm.create", "gorm.query", "gorm.update", "gorm.delete", "gorm.row", "gorm.raw"}
+	if len(spans) != len(wantNames) {
+		t.Fatalf("期望 %d 个 Span，实际 %d 个", len(wantNames), len(spans))
+	}
+	for i, span := range spans {
+		if span.Name() != wantNames[i] {
+			t.Errorf("第 %d 个 Span 名称 = %q，期望 %q", i, span.Name(), wantNames[i])
+		}
+		if span.Parent().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("%s 没有挂在调用方的 Span 下", span.Name())
+		}
+		if v, _ := spanAttr(span, dbSystemKey); v.AsString() != "sqlite" {
+			t.Errorf("%s db.system = %q", span.Name(), v.AsString())
+		}
+		if v, _ := spanAttr(span, dbStatementKey); v.AsString() == "" {
+			t.Errorf("%s 缺少 db.statement", span.Name())
+		}
+		if span.Status().Code == codes.Error {
+			t.Errorf("%s 不应标记为错误: %s", span.Name(), span.Status().Description)
+		}
+	}
+
+	create := spans[0]
+	if v, _ := spanAttr(create, dbTableKey); v.AsString() != "traced_users" {
+		t.Errorf("db.sql.table = %q", v.AsString())
+	}
+	if v, _ := spanAttr(create, dbRowsAffectedKey); v.AsInt64() != 1 {
+		t.Errorf("db.rows_affected = %d", v.AsInt64())
+	}
+	if v, _ := spanAttr(spans[1], dbStatementKey); !strings.Contains(v.AsString(), `"alice"`) {
+		t.Errorf("默认应记录插值后的 SQL，实际 %q", v.AsString())
+	}
+	if _, ok := spanAttr(create, dbSlowQueryKey); ok {
+		t.Error("快速查询不应标记为慢查询")
+	}
+}
+
+func TestGormTracingPluginErrorStatus(t *testing.T) {
+	db, recorder, _ := newTracedDB(t, config.GormLogConfig{})
+
+	err := db.Exec("SELECT * FROM missing_table").Error
+	if err == nil {
+		t.Fatal("期望查询不存在的表返回错误")
+	}
+	var got tracedUser
+	if err := db.First(&got, 999).Error; err != gorm.ErrRecordNotFound {
+		t.Fatalf("First: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("期望 2 个 Span，实际 %d 个", len(spans))
+	}
+	if st := spans[0].Status(); st.Code != codes.Error || st.Description != err.Error() {
+		t.Errorf("SQL 错误的 Span 状态 = %+v", st)
+	}
+	if len(spans[0].Events()) == 0 || spans[0].Events()[0].Name != "exception" {
+		t.Error("SQL 错误应记录 exception 事件")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Error("未开启 IgnoreRecordNotFoundError 时 record not found 应标记为错误")
+	}
+}
+
+func TestGormTracingPluginIgnoreRecordNotFound(t *testing.T) {
+	db, recorder, _ := newTracedDB(t, config.GormLogConfig{IgnoreRecordNotFoundError: true})
+
+	var got tracedUser
+	_ = db.First(&got, 999).Error
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("期望 1 个 Span，实际 %d 个", len(spans))
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Error("开启 IgnoreRecordNotFoundError 后 record not found 不应标记为错误")
+	}
+}
+
+func TestGormTracingPluginRedaction(t *testing.T) {
+	t.Run("TraceRedactParams", func(t *testing.T) {
+		db, recorder, _ := newTracedDB(t, config.GormLogConfig{TraceRedactParams: true})
+		var users []tracedUser
+		db.Where("phone = ?", "13500000000").Find(&users)
+
+		stmt, _ := spanAttr(recorder.Ended()[0], dbStatementKey)
+		if strings.Contains(stmt.AsString(), "13500000000") || !strings.Contains(stmt.AsString(), "?") {
+			t.Errorf("db.statement 应只包含占位符，实际 %q", stmt.AsString())
+		}
+	})
+
+	t.Run("RedactColumns", func(t *testing.T) {
+		db, recorder, _ := newTracedDB(t, config.GormLogConfig{
+			RedactColumns: map[string][]string{"traced_users": {"phone"}},
+		})
+		var users []tracedUser
+		db.Where("name = ? AND phone = ?", "alice", "13500000000").Find(&users)
+
+		stmt, _ := spanAttr(recorder.Ended()[0], dbStatementKey)
+		if strings.Contains(stmt.AsString(), "13500000000") || !strings.Contains(stmt.AsString(), `"alice"`) {
+			t.Errorf("只有 phone 列应被脱敏，实际 %q", stmt.AsString())
+		}
+	})
+}
+
+func TestGormTracingPluginSlowQuery(t *testing.T) {
+	db, recorder, plugin := newTracedDB(t, config.GormLogConfig{})
+	plugin.slowThreshold = time.Nanosecond
+
+	var users []tracedUser
+	db.Find(&users)
+
+	if v, ok := spanAttr(recorder.Ended()[0], dbSlowQueryKey); !ok || !v.AsBool() {
+		t.Error("超过阈值的查询应标记 db.slow_query=true")
+	}
+}
@@ -0,0 +1,194 @@
+package identity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+)
+
+// 网关转发给下游服务的用户身份请求头
+const (
+	HeaderUserID     = "X-User-ID"
+	HeaderUserRole   = "X-User-Role"
+	HeaderUserStatus = "X-User-Status"
+	HeaderPlatform   = "X-Platform"
+)
+
+// 身份签名使用的请求头
+const (
+	HeaderTimestamp = "X-Identity-Timestamp" // 签名时间 (Unix 秒)
+	HeaderKeyID     = "X-Identity-Key-ID"    // 签名使用的密钥 ID
+	HeaderSignature = "X-Identity-Signature" // base64url 编码的 HMAC-SHA256 签名
+)
+
+const (
+	// signatureVersion 是签名串的版本前缀，签名串格式变化时递增
+	signatureVersion = "v1"
+	// minKeyLength 是密钥的最小字节数
+	minKeyLength = 32
+	// defaultReplayWindow 是未配置 ReplayWindow 时允许的时间偏差
+	defaultReplayWindow = 60 * time.Second
+)
+
+// 校验失败的原因，UserContextMiddleware 会把它们统一转换为 401 响应
+var (
+	ErrMissingSignature = errors.New("身份请求头缺少签名")
+	ErrUnknownKeyID     = errors.New("身份签名使用了未知的密钥 ID")
+	ErrSignatureExpired = errors.New("身份签名已过期或时间戳无效")
+	ErrInvalidSignature = errors.New("身份签名无效")
+)
+
+// Identity 是需要签名的身份字段，取值与请求头中的字符串一致
+type Identity struct {
+	UserID   string
+	Role     string
+	Status   string
+	Platform string
+}
+
+// FromHeader 从请求头读取身份字段
+func FromHeader(h http.Header) Identity {
+	return Identity{
+		UserID:   h.Get(HeaderUserID),
+		Role:     h.Get(HeaderUserRole),
+		Status:   h.Get(HeaderUserStatus),
+		Platform: h.Get(HeaderPlatform),
+	}
+}
+
+// Signature 是一次身份签名的结果，HTTP 请求头之外的载体 (例如 Baggage) 可以分别保存这三个字段
+type Signature struct {
+	KeyID     string
+	Timestamp string // 签名时间 (Unix 秒)
+	Value     string // base64url 编码的 HMAC-SHA256 签名
+}
+
+// Signer 为转发给下游服务的请求写入身份请求头和签名，由网关使用，并发安全
+type Signer struct {
+	keyID string
+	key   []byte
+}
+
+// NewSigner 根据配置创建签名器，cfg.KeyID 必须存在于 cfg.Keys 中
+func NewSigner(cfg config.IdentitySigningConfig) (*Signer, error) {
+	if cfg.KeyID == "" {
+		return nil, errors.New("身份签名配置缺少 keyID")
+	}
+	key, ok := cfg.Keys[cfg.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("身份签名密钥 %q 不存在于 keys 中", cfg.KeyID)
+	}
+	if err := validateKey(cfg.KeyID, key); err != nil {
+		return nil, err
+	}
+	return &Signer{keyID: cfg.KeyID, key: []byte(key)}, nil
+}
+
+// Sign 把身份字段、签名时间、密钥 ID 和签名写入请求头 (覆盖请求中已有的同名请求头，客户端伪造的身份不会被转发)
+// 例如网关在鉴权之后: signer.Sign(req.Header, identity.Identity{UserID: uid, Role: "user", Status: "active", Platform: "web"})
+func (s *Signer) Sign(h http.Header, id Identity) {
+	sig := s.SignIdentity(id)
+	setOrDelete(h, HeaderUserID, id.UserID)
+	setOrDelete(h, HeaderUserRole, id.Role)
+	setOrDelete(h, HeaderUserStatus, id.Status)
+	setOrDelete(h, HeaderPlatform, id.Platform)
+	h.Set(HeaderTimestamp, sig.Timestamp)
+	h.Set(HeaderKeyID, sig.KeyID)
+	h.Set(HeaderSignature, sig.Value)
+}
+
+// SignIdentity 使用当前时间对身份字段签名
+func (s *Signer) SignIdentity(id Identity) Signature {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	return Signature{KeyID: s.keyID, Timestamp: ts, Value: sign(s.key, s.keyID, ts, id)}
+}
+
+// Verifier 校验身份请求头的签名，由下游服务使用，并发安全
+type Verifier struct {
+	keys   map[string][]byte
+	window time.Duration
+}
+
+// NewVerifier 根据配置创建校验器，cfg.Keys 中的每个密钥都会被接受
+func NewVerifier(cfg config.IdentitySigningConfig) (*Verifier, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("身份签名配置缺少 keys")
+	}
+	v := &Verifier{keys: make(map[string][]byte, len(cfg.Keys)), window: cfg.ReplayWindow}
+	for id, key := range cfg.Keys {
+		if err := validateKey(id, key); err != nil {
+			return nil, err
+		}
+		v.keys[id] = []byte(key)
+	}
+	if v.window <= 0 {
+		v.window = defaultReplayWindow
+	}
+	return v, nil
+}
+
+// Verify 校验请求头中的身份签名，成功时返回签名覆盖的身份字段
+// 签名时间与当前时间的偏差超过 ReplayWindow 时返回 ErrSignatureExpired，限制被截获的请求头可以被重放的时间。
+func (v *Verifier) Verify(h http.Header) (Identity, error) {
+	id := FromHeader(h)
+	return id, v.VerifyIdentity(id, Signature{
+		KeyID:     h.Get(HeaderKeyID),
+		Timestamp: h.Get(HeaderTimestamp),
+		Value:     h.Get(HeaderSignature),
+	})
+}
+
+// VerifyIdentity 校验身份字段的签名，错误含义与 Verify 相同
+func (v *Verifier) VerifyIdentity(id Identity, sig Signature) error {
+	if sig.Value == "" {
+		return ErrMissingSignature
+	}
+	key, ok := v.keys[sig.KeyID]
+	if !ok {
+		return ErrUnknownKeyID
+	}
+	sec, err := strconv.ParseInt(sig.Timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureExpired
+	}
+	if skew := time.Since(time.Unix(sec, 0)); skew > v.window || skew < -v.window {
+		return ErrSignatureExpired
+	}
+	if !hmac.Equal([]byte(sig.Value), []byte(sign(key, sig.KeyID, sig.Timestamp, id))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// sign 计算签名: HMAC-SHA256(key, "v1\n<keyID>\n<timestamp>\n<userID>\n<role>\n<status>\n<platform>")
+// 请求头的值不能包含换行，用换行分隔可以保证不同字段组合不会得到相同的签名串。
+func sign(key []byte, keyID, ts string, id Identity) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{signatureVersion, keyID, ts, id.UserID, id.Role, id.Status, id.Platform}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validateKey 校验密钥长度
+func validateKey(id, key string) error {
+	if len(key) < minKeyLength {
+		return fmt.Errorf("身份签名密钥 %q 长度不足 %d 字节", id, minKeyLength)
+	}
+	return nil
+}
+
+// setOrDelete 设置请求头，值为空时删除
+func setOrDelete(h http.Header, key, value string) {
+	if value == "" {
+		h.Del(key)
+		return
+	}
+	h.Set(key, value)
+}
@@ -0,0 +1,199 @@
+package identity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+)
+
+var (
+	key1 = strings.Repeat("a", 32)
+	key2 = strings.Repeat("b", 32)
+
+	testIdentity = Identity{UserID: "u-1", Role: "user", Status: "active", Platform: "web"}
+)
+
+func newTestSigner(t *testing.T, cfg config.IdentitySigningConfig) *Signer {
+	t.Helper()
+	s, err := NewSigner(cfg)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return s
+}
+
+func newTestVerifier(t *testing.T, cfg config.IdentitySigningConfig) *Verifier {
+	t.Helper()
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+// signedHeader 返回用 k1 签名的身份请求头
+func signedHeader(t *testing.T) http.Header {
+	t.Helper()
+	h := http.Header{}
+	newTestSigner(t, config.IdentitySigningConfig{KeyID: "k1", Keys: map[string]string{"k1": key1}}).Sign(h, testIdentity)
+	return h
+}
+
+// TestSignVerifyRoundTrip 签名后的请求头可以通过校验，并覆盖请求中已有的身份请求头
+func TestSignVerifyRoundTrip(t *testing.T) {
+	h := http.Header{}
+	h.Set(HeaderUserRole, "admin") // 客户端伪造的请求头会被覆盖
+	h.Set(HeaderPlatform, "ios")
+	id := Identity{UserID: "u-1", Role: "user", Status: "active"}
+	newTestSigner(t, config.IdentitySigningConfig{KeyID: "k1", Keys: map[string]string{"k1": key1}}).Sign(h, id)
+
+	if h.Get(HeaderUserRole) != "user" || h.Get(HeaderPlatform) != "" || h.Get(HeaderKeyID) != "k1" {
+		t.Errorf("签名请求头错误: %v", h)
+	}
+	got, err := newTestVerifier(t, config.IdentitySigningConfig{Keys: map[string]string{"k1": key1}}).Verify(h)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got != id {
+		t.Errorf("Verify 返回的身份 = %+v，期望 %+v", got, id)
+	}
+}
+
+// TestVerifyTampered 篡改任一被签名的字段都会导致校验失败
+func TestVerifyTampered(t *testing.T) {
+	v := newTestVerifier(t, config.IdentitySigningConfig{Keys: map[string]string{"k1": key1}})
+	cases := map[string]string{
+		HeaderUserID:     "u-2",
+		HeaderUserRole:   "admin",
+		HeaderUserStatus: "blacklisted",
+		HeaderPlatform:   "ios",
+	}
+	for header, value := range cases {
+		h := signedHeader(t)
+		h.Set(header, value)
+		if _, err := v.Verify(h); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("篡改 %s 应返回 ErrInvalidSignature，实际 %v", header, err)
+		}
+	}
+
+	h := signedHeader(t)
+	h.Del(HeaderPlatform)
+	if _, err := v.Verify(h); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("删除已签名的平台请求头应返回 ErrInvalidSignature，实际 %v", err)
+	}
+}
+
+// TestVerifyMalformed 签名缺失、格式错误、密钥未知或时间戳无效时返回对应的错误
+func TestVerifyMalformed(t *testing.T) {
+	v := newTestVerifier(t, config.IdentitySigningConfig{Keys: map[string]string{"k1": key1}})
+	cases := []struct {
+		name    string
+		mutate  func(h http.Header)
+		wantErr error
+	}{
+		{"缺少签名", func(h http.Header) { h.Del(HeaderSignature) }, ErrMissingSignature},
+		{"签名格式错误", func(h http.Header) { h.Set(HeaderSignature, "not-a-signature") }, ErrInvalidSignature},
+		{"未知密钥 ID", func(h http.Header) { h.Set(HeaderKeyID, "k9") }, ErrUnknownKeyID},
+		{"缺少密钥 ID", func(h http.Header) { h.Del(HeaderKeyID) }, ErrUnknownKeyID},
+		{"时间戳格式错误", func(h http.Header) { h.Set(HeaderTimestamp, "yesterday") }, ErrSignatureExpired},
+		{"缺少时间戳", func(h http.Header) { h.Del(HeaderTimestamp) }, ErrSignatureExpired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := signedHeader(t)
+			tc.mutate(h)
+			if _, err := v.Verify(h); !errors.Is(err, tc.wantErr) {
+				t.Errorf("应返回 %v，实际 %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestVerifyReplayWindow 签名时间与当前时间的偏差 (过去或未来) 超过 ReplayWindow 时返回 ErrSignatureExpired
+func TestVerifyReplayWindow(t *testing.T) {
+	v := newTestVerifier(t, config.IdentitySigningConfig{Keys: map[string]string{"k1": key1}, ReplayWindow: time.Minute})
+	now := time.Now()
+	cases := []struct {
+		name    string
+		offset  time.Duration
+		wantErr error
+	}{
+		{"窗口内 (过去)", -30 * time.Second, nil},
+		{"窗口内 (未来)", 30 * time.Second, nil},
+		{"超出窗口 (过去)", -2 * time.Minute, ErrSignatureExpired},
+		{"超出窗口 (未来)", 2 * time.Minute, ErrSignatureExpired},
+	}
+	for _, tc := range cases {
+		ts := strconv.FormatInt(now.Add(tc.offset).Unix(), 10)
+		sig := Signature{KeyID: "k1", Timestamp: ts, Value: sign([]byte(key1), "k1", ts, testIdentity)}
+		if err := v.VerifyIdentity(testIdentity, sig); !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: 应返回 %v，实际 %v", tc.name, tc.wantErr, err)
+		}
+	}
+
+	if v := newTestVerifier(t, config.IdentitySigningConfig{Keys: map[string]string{"k1": key1}}); v.window != defaultReplayWindow {
+		t.Errorf("未配置 ReplayWindow 时应默认为 %v，实际 %v", defaultReplayWindow, v.window)
+	}
+}
+
+// TestKeyRotation 轮换密钥时校验器同时接受新旧密钥，签名器使用新的 KeyID
+func TestKeyRotation(t *testing.T) {
+	oldHeader := signedHeader(t)
+
+	rotated := config.IdentitySigningConfig{KeyID: "k2", Keys: map[string]string{"k1": key1, "k2": key2}}
+	newHeader := http.Header{}
+	newTestSigner(t, rotated).Sign(newHeader, testIdentity)
+	if newHeader.Get(HeaderKeyID) != "k2" {
+		t.Errorf("轮换后应使用 k2 签名，实际 %q", newHeader.Get(HeaderKeyID))
+	}
+
+	v := newTestVerifier(t, rotated)
+	if _, err := v.Verify(oldHeader); err != nil {
+		t.Errorf("轮换期间旧密钥的签名仍应有效，实际 %v", err)
+	}
+	if _, err := v.Verify(newHeader); err != nil {
+		t.Errorf("新密钥的签名应有效，实际 %v", err)
+	}
+
+	// 旧密钥下线后，旧签名因密钥未知被拒绝
+	v = newTestVerifier(t, config.IdentitySigningConfig{Keys: map[string]string{"k2": key2}})
+	if _, err := v.Verify(oldHeader); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("下线的密钥应返回 ErrUnknownKeyID，实际 %v", err)
+	}
+
+	// 伪造 KeyID 指向另一个密钥同样无法通过
+	oldHeader.Set(HeaderKeyID, "k2")
+	if _, err := newTestVerifier(t, rotated).Verify(oldHeader); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("KeyID 与签名密钥不符应返回 ErrInvalidSignature，实际 %v", err)
+	}
+}
+
+// TestNewSignerVerifierConfig 配置缺失或密钥短于 32 字节时返回错误
+func TestNewSignerVerifierConfig(t *testing.T) {
+	short := strings.Repeat("a", minKeyLength-1)
+	signerCases := map[string]config.IdentitySigningConfig{
+		"缺少 keyID":  {Keys: map[string]string{"k1": key1}},
+		"keyID 不存在": {KeyID: "k2", Keys: map[string]string{"k1": key1}},
+		"密钥过短":      {KeyID: "k1", Keys: map[string]string{"k1": short}},
+	}
+	for name, cfg := range signerCases {
+		if _, err := NewSigner(cfg); err == nil {
+			t.Errorf("NewSigner %s: 应返回错误", name)
+		}
+	}
+
+	verifierCases := map[string]config.IdentitySigningConfig{
+		"缺少 keys": {},
+		"任一密钥过短":  {Keys: map[string]string{"k1": key1, "k2": short}},
+	}
+	for name, cfg := range verifierCases {
+		if _, err := NewVerifier(cfg); err == nil {
+			t.Errorf("NewVerifier %s: 应返回错误", name)
+		}
+	}
+}
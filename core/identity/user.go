@@ -0,0 +1,88 @@
+package identity
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/models/enums"
+)
+
+// 身份字段缺失的原因
+var (
+	ErrMissingUserID = errors.New("缺少用户 ID")
+	ErrMissingRole   = errors.New("缺少用户角色")
+	ErrMissingStatus = errors.New("缺少用户状态")
+)
+
+// UserContext 是解析并校验后的当前用户身份
+// middleware.UserContextMiddleware (HTTP 请求头) 和 tracing.ExtractUserBaggage (Baggage) 都会把它写入 context
+type UserContext struct {
+	UserID   string
+	Role     enums.UserRole
+	Status   enums.UserStatus
+	Platform enums.Platform // 未携带平台时为空
+}
+
+// IsAdmin 判断当前用户是否为管理员
+func (u *UserContext) IsAdmin() bool {
+	return u.Role == enums.RoleAdmin
+}
+
+// Parse 解析并校验身份字段
+// - UserID、Role、Status 必填，Platform 可选
+// - Role / Status 只接受 enums 中的名称 (e.g., "admin"、"active")，不接受数值: 枚举的零值分别是 RoleAdmin 和 StatusActive，
+// 缺失或无法识别的值不能退化为零值，否则缺少状态的请求会被当作活跃用户，角色 "0" 会被当作管理员
+func Parse(id Identity) (*UserContext, error) {
+	user := &UserContext{UserID: id.UserID}
+	if user.UserID == "" {
+		return nil, ErrMissingUserID
+	}
+
+	var err error
+	if user.Role, err = parseUserRole(id.Role); err != nil {
+		return nil, err
+	}
+	if user.Status, err = parseUserStatus(id.Status); err != nil {
+		return nil, err
+	}
+	if id.Platform != "" {
+		if user.Platform, err = enums.PlatformFromString(id.Platform); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// ContextWithUser 把用户身份写入 context: constants.UserContextKey 保存 *UserContext，
+// 各字段的字符串形式写入 constants.UserIDKey / RoleKey / StatusKey / PlatformKey
+func ContextWithUser(ctx context.Context, user *UserContext) context.Context {
+	ctx = context.WithValue(ctx, constants.UserContextKey, user)
+	ctx = context.WithValue(ctx, constants.UserIDKey, user.UserID)
+	ctx = context.WithValue(ctx, constants.RoleKey, user.Role.String())
+	ctx = context.WithValue(ctx, constants.StatusKey, user.Status.String())
+	ctx = context.WithValue(ctx, constants.PlatformKey, string(user.Platform))
+	return ctx
+}
+
+// UserFromContext 返回 ContextWithUser 写入的用户身份，第二个返回值为 false 表示匿名
+func UserFromContext(ctx context.Context) (*UserContext, bool) {
+	user, ok := ctx.Value(constants.UserContextKey).(*UserContext)
+	return user, ok
+}
+
+// parseUserRole 解析角色名称 ("admin")，角色必填
+func parseUserRole(s string) (enums.UserRole, error) {
+	if s == "" {
+		return 0, ErrMissingRole
+	}
+	return enums.RoleFromString(s)
+}
+
+// parseUserStatus 解析状态名称 ("active")，状态必填
+func parseUserStatus(s string) (enums.UserStatus, error) {
+	if s == "" {
+		return 0, ErrMissingStatus
+	}
+	return enums.StatusFromString(s)
+}
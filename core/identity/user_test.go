@@ -0,0 +1,72 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/models/enums"
+)
+
+// TestParse 角色和状态必填且只接受名称，缺失或无法识别的值不会退化为枚举零值 (管理员/活跃)
+func TestParse(t *testing.T) {
+	valid := Identity{UserID: "u-1", Role: "user", Status: "blacklisted", Platform: "web"}
+	user, err := Parse(valid)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if user.UserID != "u-1" || user.Role != enums.RoleUser || user.Status != enums.StatusBlacklisted || user.Platform != enums.PlatformWeb {
+		t.Errorf("解析结果错误: %+v", user)
+	}
+
+	cases := []struct {
+		name    string
+		id      Identity
+		wantErr error
+	}{
+		{"缺少用户 ID", Identity{Role: "user", Status: "active"}, ErrMissingUserID},
+		{"缺少角色", Identity{UserID: "u-1", Status: "active"}, ErrMissingRole},
+		{"缺少状态", Identity{UserID: "u-1", Role: "admin"}, ErrMissingStatus},
+		{"数值角色", Identity{UserID: "u-1", Role: "0", Status: "active"}, nil},
+		{"未知角色", Identity{UserID: "u-1", Role: "root", Status: "active"}, nil},
+		{"数值状态", Identity{UserID: "u-1", Role: "user", Status: "0"}, nil},
+		{"未知状态", Identity{UserID: "u-1", Role: "user", Status: "deleted"}, nil},
+		{"未知平台", Identity{UserID: "u-1", Role: "user", Status: "active", Platform: "tv"}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := Parse(tc.id)
+			if err == nil {
+				t.Fatalf("应返回错误，实际解析为 %+v", user)
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("错误应为 %v，实际 %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestContextWithUser 除 *UserContext 外还写入各字段的字符串键，实体审计钩子等不依赖本包的代码据此读取用户 ID
+func TestContextWithUser(t *testing.T) {
+	user := &UserContext{UserID: "u-1", Role: enums.RoleUser, Status: enums.StatusActive, Platform: enums.PlatformWeb}
+	ctx := ContextWithUser(context.Background(), user)
+
+	if got, ok := UserFromContext(ctx); !ok || got != user {
+		t.Errorf("UserFromContext 应返回写入的身份，实际 %+v", got)
+	}
+	want := map[interface{}]string{
+		constants.UserIDKey:   "u-1",
+		constants.RoleKey:     "user",
+		constants.StatusKey:   "active",
+		constants.PlatformKey: "web",
+	}
+	for k, v := range want {
+		if got := ctx.Value(k); got != v {
+			t.Errorf("%s 应为 %q，实际 %v", k, v, got)
+		}
+	}
+	if _, ok := UserFromContext(context.Background()); ok {
+		t.Error("空 context 应视为匿名")
+	}
+}
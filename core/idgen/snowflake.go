@@ -0,0 +1,127 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+)
+
+// Snowflake ID 的位布局: 1 位符号位 (恒为 0) | 41 位毫秒时间戳 | 10 位节点 ID | 12 位序列号
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+
+	MaxNodeID   = 1<<nodeBits - 1     // 节点 ID 的最大值 (1023)
+	maxSequence = 1<<sequenceBits - 1 // 每毫秒的最大序列号 (4095)
+
+	timestampShift = nodeBits + sequenceBits
+	nodeShift      = sequenceBits
+
+	// maxClockBackward 是可以等待追平的最大时钟回拨，超过时 NextID 返回错误
+	maxClockBackward = 10 * time.Millisecond
+)
+
+// Epoch 是 Snowflake 时间戳的起点 (2024-01-01 00:00:00 UTC)，41 位毫秒时间戳可用到 2093 年
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake 是一个按节点区分的 Snowflake ID 生成器，并发安全
+// 生成的 ID 按时间单调递增，同一节点每毫秒最多生成 4096 个
+type Snowflake struct {
+	mu       sync.Mutex
+	nodeID   int64
+	lastMs   int64
+	sequence int64
+}
+
+// NewSnowflake 创建指定节点 ID 的生成器，nodeID 必须在 [0, MaxNodeID] 范围内
+func NewSnowflake(nodeID int64) (*Snowflake, error) {
+	if nodeID < 0 || nodeID > MaxNodeID {
+		return nil, fmt.Errorf("snowflake 节点 ID 必须在 0-%d 之间，当前值: %d", MaxNodeID, nodeID)
+	}
+	return &Snowflake{nodeID: nodeID, lastMs: -1}, nil
+}
+
+// NextID 生成下一个 ID
+// - 同一毫秒内序列号用尽时等待到下一毫秒
+// - 时钟回拨不超过 10ms 时等待追平，超过时返回错误，避免生成重复 ID
+func (s *Snowflake) NextID() (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := sinceEpochMs()
+	if now < s.lastMs {
+		backward := time.Duration(s.lastMs-now) * time.Millisecond
+		if backward > maxClockBackward {
+			return 0, fmt.Errorf("系统时钟回拨 %s，拒绝生成 snowflake ID", backward)
+		}
+		time.Sleep(backward)
+		now = sinceEpochMs()
+		if now < s.lastMs { // 等待后仍未追平 (时钟持续回拨)，沿用上一个时间戳
+			now = s.lastMs
+		}
+	}
+
+	if now == s.lastMs {
+		s.sequence = (s.sequence + 1) & maxSequence
+		if s.sequence == 0 { // 当前毫秒序列号用尽，等待下一毫秒
+			for now <= s.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				now = sinceEpochMs()
+			}
+		}
+	} else {
+		s.sequence = 0
+	}
+	s.lastMs = now
+
+	return uint64(now<<timestampShift | s.nodeID<<nodeShift | s.sequence), nil
+}
+
+// sinceEpochMs 返回当前时间距 Epoch 的毫秒数
+func sinceEpochMs() int64 {
+	return time.Since(Epoch).Milliseconds()
+}
+
+// Parse 拆解一个 Snowflake ID，返回生成时间、节点 ID 和序列号 (用于排查问题)
+func Parse(id uint64) (time.Time, int64, int64) {
+	ms := int64(id >> timestampShift)
+	node := int64(id>>nodeShift) & MaxNodeID
+	seq := int64(id) & maxSequence
+	return Epoch.Add(time.Duration(ms) * time.Millisecond), node, seq
+}
+
+// ErrNotInitialized 表示调用包级 NextID 之前没有调用 Init
+// 默认生成器不会退回到节点 0: 多个未初始化的实例使用同一节点会生成重复 ID
+var ErrNotInitialized = errors.New("idgen 未初始化: 服务启动时需要调用 idgen.Init 配置节点 ID")
+
+var (
+	defaultMu        sync.RWMutex
+	defaultSnowflake *Snowflake // 由 Init 设置，未初始化时为 nil
+)
+
+// Init 根据配置初始化包级默认生成器，服务启动时调用一次
+// 多实例部署时每个实例必须配置不同的 NodeID，否则可能生成重复 ID
+func Init(cfg config.IDGenConfig) error {
+	s, err := NewSnowflake(cfg.NodeID)
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defaultSnowflake = s
+	defaultMu.Unlock()
+	return nil
+}
+
+// NextID 使用包级默认生成器生成下一个 Snowflake ID，没有调用过 Init 时返回 ErrNotInitialized
+func NextID() (uint64, error) {
+	defaultMu.RLock()
+	s := defaultSnowflake
+	defaultMu.RUnlock()
+	if s == nil {
+		return 0, ErrNotInitialized
+	}
+	return s.NextID()
+}
@@ -0,0 +1,59 @@
+package idgen
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+)
+
+func TestNextIDRequiresInit(t *testing.T) {
+	defaultMu.Lock()
+	saved := defaultSnowflake
+	defaultSnowflake = nil
+	defaultMu.Unlock()
+	t.Cleanup(func() {
+		defaultMu.Lock()
+		defaultSnowflake = saved
+		defaultMu.Unlock()
+	})
+
+	if _, err := NextID(); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("未调用 Init 时应返回 ErrNotInitialized，实际 %v", err)
+	}
+	if err := Init(config.IDGenConfig{NodeID: MaxNodeID + 1}); err == nil {
+		t.Fatal("超出范围的节点 ID 应返回错误")
+	}
+	if _, err := NextID(); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("Init 失败后仍应返回 ErrNotInitialized，实际 %v", err)
+	}
+
+	if err := Init(config.IDGenConfig{NodeID: 7}); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	id, err := NextID()
+	if err != nil {
+		t.Fatalf("NextID: %v", err)
+	}
+	if _, node, _ := Parse(id); node != 7 {
+		t.Errorf("ID 中的节点 = %d，期望 7", node)
+	}
+}
+
+func TestSnowflakeMonotonic(t *testing.T) {
+	s, err := NewSnowflake(1)
+	if err != nil {
+		t.Fatalf("NewSnowflake: %v", err)
+	}
+	var last uint64
+	for i := 0; i < 10000; i++ {
+		id, err := s.NextID()
+		if err != nil {
+			t.Fatalf("NextID: %v", err)
+		}
+		if id <= last {
+			t.Fatalf("ID 应单调递增: %d <= %d", id, last)
+		}
+		last = id
+	}
+}
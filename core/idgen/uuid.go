@@ -0,0 +1,13 @@
+package idgen
+
+import "github.com/google/uuid"
+
+// NewUUIDv7 生成一个 UUIDv7 字符串 (RFC 9562)
+// UUIDv7 以毫秒时间戳开头，按生成时间大致有序，作为主键时比 UUIDv4 对 B+ 树索引更友好，且不需要分配节点 ID
+func NewUUIDv7() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// condition 是一个过滤条件，列名在应用到查询前会经过白名单校验
+type condition struct {
+	column string
+	build  func(col clause.Column) clause.Expression
+}
+
+// Filter 是类型安全的过滤条件构造器，多个条件之间为 AND 关系
+// 列名使用数据库列名 (e.g., "author_id")，只有 Repository 白名单中的列才允许使用:
+//
+//	filter := repository.NewFilter().Eq("author_id", uid).Gte("created_at", since).In("status", 1, 2)
+type Filter struct {
+	conds []condition
+}
+
+// NewFilter 创建一个空的过滤条件
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+// add 追加一个条件
+func (f *Filter) add(column string, build func(col clause.Column) clause.Expression) *Filter {
+	f.conds = append(f.conds, condition{column: column, build: build})
+	return f
+}
+
+// Eq 列等于 value (value 为 nil 时生成 IS NULL)
+func (f *Filter) Eq(column string, value any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Eq{Column: col, Value: value} })
+}
+
+// Ne 列不等于 value (value 为 nil 时生成 IS NOT NULL)
+func (f *Filter) Ne(column string, value any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Neq{Column: col, Value: value} })
+}
+
+// Gt 列大于 value
+func (f *Filter) Gt(column string, value any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Gt{Column: col, Value: value} })
+}
+
+// Gte 列大于等于 value
+func (f *Filter) Gte(column string, value any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Gte{Column: col, Value: value} })
+}
+
+// Lt 列小于 value
+func (f *Filter) Lt(column string, value any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Lt{Column: col, Value: value} })
+}
+
+// Lte 列小于等于 value
+func (f *Filter) Lte(column string, value any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Lte{Column: col, Value: value} })
+}
+
+// In 列的值在 values 中
+func (f *Filter) In(column string, values ...any) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.IN{Column: col, Values: values} })
+}
+
+// Like 列匹配 LIKE 模式 (调用方负责添加 % 通配符)
+func (f *Filter) Like(column string, pattern string) *Filter {
+	return f.add(column, func(col clause.Column) clause.Expression { return clause.Like{Column: col, Value: pattern} })
+}
+
+// SortField 是一个排序字段
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// Asc 按列升序
+func Asc(column string) SortField {
+	return SortField{Column: column}
+}
+
+// Desc 按列降序
+func Desc(column string) SortField {
+	return SortField{Column: column, Desc: true}
+}
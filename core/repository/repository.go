@@ -0,0 +1,394 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"gorm.io/plugin/dbresolver"
+)
+
+// 分页参数的默认值和上限
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrColumnNotAllowed 表示过滤或排序使用了不在白名单中的列
+var ErrColumnNotAllowed = errors.New("列不允许用于过滤或排序")
+
+// ErrInvalidCursor 表示游标无法解析 (被篡改或来自其他模型)
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// Repository 是基于 GORM 的泛型仓储，T 为嵌入 entities.BaseModel (或 SnowflakeModel 等) 的模型类型
+// - 所有方法都使用 db.WithContext(ctx)，链路追踪、SQL 日志级别覆盖、读写分离的强制主库等都能生效
+// - gorm.ErrRecordNotFound 统一映射为 commonerrors.ErrRepoNotFound，其他错误附带操作名后原样包装 (可用 errors.Is 判断)
+// - 过滤和排序只允许使用白名单中的列 (WithAllowedColumns)，默认白名单为空，只允许主键列
+type Repository[T any] struct {
+	db      *gorm.DB
+	schema  *schema.Schema
+	pk      *schema.Field
+	allowed map[string]struct{}
+}
+
+// Option 定义 New 的可选配置项
+type Option func(*repoOptions)
+
+// repoOptions 保存 New 的可选配置
+type repoOptions struct {
+	allowedColumns []string
+}
+
+// WithAllowedColumns 设置可用于过滤和排序的列 (数据库列名)，主键列始终允许
+// 默认白名单为空: 列表接口常把前端传入的字段名直接交给 Filter/SortField，需要显式列出允许的列，避免按未加索引或敏感的列排序、过滤
+func WithAllowedColumns(columns ...string) Option {
+	return func(o *repoOptions) {
+		o.allowedColumns = columns
+	}
+}
+
+// New 为模型 T 创建仓储，模型必须有且只有一个主键
+func New[T any](db *gorm.DB, opts ...Option) (*Repository[T], error) {
+	o := &repoOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("解析模型 %T 失败: %w", *new(T), err)
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return nil, fmt.Errorf("模型 %s 缺少唯一主键", stmt.Schema.Name)
+	}
+
+	allowed := make(map[string]struct{}, len(o.allowedColumns))
+	for _, col := range o.allowedColumns {
+		if _, ok := stmt.Schema.FieldsByDBName[col]; !ok {
+			return nil, fmt.Errorf("模型 %s 不存在列 %s", stmt.Schema.Name, col)
+		}
+		allowed[col] = struct{}{}
+	}
+
+	return &Repository[T]{db: db, schema: stmt.Schema, pk: pk, allowed: allowed}, nil
+}
+
+// WithTx 返回绑定到事务 tx 的仓储副本，用于在 db.Transaction 中组合多个仓储操作
+func (r *Repository[T]) WithTx(tx *gorm.DB) *Repository[T] {
+	clone := *r
+	clone.db = tx
+	return &clone
+}
+
+// DB 返回带 ctx 的 *gorm.DB，用于仓储未覆盖的查询
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx)
+}
+
+// Get 按主键查询，不存在 (或已软删除) 时返回 commonerrors.ErrRepoNotFound
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	entity := new(T)
+	if err := r.db.WithContext(ctx).Where(r.pkEq(id)).Take(entity).Error; err != nil {
+		return nil, mapError("get", err)
+	}
+	return entity, nil
+}
+
+// List 按过滤条件和排序查询全部匹配的记录 (不分页，结果集可能较大时请使用 ListPage 或 ListAfter)
+func (r *Repository[T]) List(ctx context.Context, filter *Filter, sort ...SortField) ([]T, error) {
+	tx, err := r.query(ctx, filter, sort)
+	if err != nil {
+		return nil, err
+	}
+	var items []T
+	if err := tx.Find(&items).Error; err != nil {
+		return nil, mapError("list", err)
+	}
+	return items, nil
+}
+
+// Create 创建记录，主键、CreatedBy 等由模型钩子填充
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return mapError("create", r.db.WithContext(ctx).Create(entity).Error)
+}
+
+// createOnlyFields 是只在创建时写入的字段，Update 不会覆盖
+var createOnlyFields = []string{"CreatedAt", "CreatedBy"}
+
+// Update 按主键更新记录的全部字段 (包括零值)
+// 主键、创建时写入的审计字段 (CreatedAt / CreatedBy) 和软删除字段不会被覆盖，软删除请使用 Delete / Restore
+// 记录不存在时返回 commonerrors.ErrRepoNotFound；模型带乐观锁 (AuditedModel) 时版本冲突返回 commonerrors.ErrVersionConflict
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	omit := []string{r.pk.Name}
+	for _, name := range createOnlyFields {
+		if f := r.schema.LookUpField(name); f != nil {
+			omit = append(omit, f.Name)
+		}
+	}
+	if f := r.deletedAtField(); f != nil {
+		omit = append(omit, f.Name)
+	}
+	tx := r.db.WithContext(ctx).Model(entity).Select("*").Omit(omit...).Updates(entity)
+	if tx.Error != nil {
+		return mapError("update", tx.Error)
+	}
+	if tx.RowsAffected > 0 {
+		return nil
+	}
+	// 没有影响任何行不代表记录不存在: MySQL 默认只统计值发生变化的行，更新为相同的值时 RowsAffected 为 0
+	exists, err := r.exists(ctx, entity)
+	if err != nil {
+		return mapError("update", err)
+	}
+	if !exists {
+		return mapError("update", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// exists 按 entity 的主键判断记录是否存在 (不含已软删除的记录)
+// 从主库读取，避免刚写入的记录因从库复制延迟被误判为不存在
+func (r *Repository[T]) exists(ctx context.Context, entity *T) (bool, error) {
+	id, zero := r.pk.ValueOf(ctx, reflect.ValueOf(entity).Elem())
+	if zero {
+		return false, nil
+	}
+	var count int64
+	err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Model(new(T)).Where(r.pkEq(id)).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// Delete 按主键删除记录；模型包含 gorm.DeletedAt 时为软删除
+func (r *Repository[T]) Delete(ctx context.Context, id any) error {
+	return r.delete(ctx, r.db, "delete", id)
+}
+
+// HardDelete 按主键物理删除记录 (包括已软删除的记录)
+func (r *Repository[T]) HardDelete(ctx context.Context, id any) error {
+	return r.delete(ctx, r.db.Unscoped(), "hard delete", id)
+}
+
+// delete 执行删除，没有删除任何行时返回 commonerrors.ErrRepoNotFound
+func (r *Repository[T]) delete(ctx context.Context, db *gorm.DB, op string, id any) error {
+	tx := db.WithContext(ctx).Where(r.pkEq(id)).Delete(new(T))
+	if tx.Error != nil {
+		return mapError(op, tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return mapError(op, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// Restore 恢复一条已软删除的记录，记录不存在或未被删除时返回 commonerrors.ErrRepoNotFound
+func (r *Repository[T]) Restore(ctx context.Context, id any) error {
+	deletedAt := r.deletedAtField()
+	if deletedAt == nil {
+		return fmt.Errorf("repository restore: 模型 %s 不支持软删除", r.schema.Name)
+	}
+	tx := r.db.WithContext(ctx).Unscoped().Model(new(T)).
+		Where(r.pkEq(id)).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: deletedAt.DBName}, Value: nil}).
+		Update(deletedAt.DBName, nil)
+	if tx.Error != nil {
+		return mapError("restore", tx.Error)
+	}
+	if tx.RowsAffected == 0 {
+		return mapError("restore", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// Page 是偏移分页的结果
+type Page[T any] struct {
+	Items    []T   `json:"items"`
+	Total    int64 `json:"total"`
+	Page     int   `json:"page"`
+	PageSize int   `json:"pageSize"`
+}
+
+// ListPage 偏移分页查询，page 从 1 开始，pageSize <= 0 时使用 DefaultPageSize，最大 MaxPageSize
+// 未指定排序时按主键升序，保证分页结果稳定
+func (r *Repository[T]) ListPage(ctx context.Context, filter *Filter, page, pageSize int, sort ...SortField) (*Page[T], error) {
+	if page < 1 {
+		page = 1
+	}
+	pageSize = clampPageSize(pageSize)
+	if len(sort) == 0 {
+		sort = []SortField{Asc(r.pk.DBName)}
+	}
+
+	tx, err := r.query(ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, mapError("count", err)
+	}
+
+	result := &Page[T]{Items: []T{}, Total: total, Page: page, PageSize: pageSize}
+	if total == 0 {
+		return result, nil
+	}
+	tx, err = r.query(ctx, filter, sort)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Offset((page - 1) * pageSize).Limit(pageSize).Find(&result.Items).Error; err != nil {
+		return nil, mapError("list page", err)
+	}
+	return result, nil
+}
+
+// CursorPage 是游标 (keyset) 分页的结果
+type CursorPage[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"` // 下一页的游标，没有更多数据时为空
+	HasMore    bool   `json:"hasMore"`
+}
+
+// ListAfter 按主键进行游标 (keyset) 分页，适合无限滚动和大表深分页
+// - cursor 为空表示第一页，之后传入上一页返回的 NextCursor
+// - desc 为 true 时按主键降序 (Snowflake / UUIDv7 主键即按创建时间从新到旧)
+// - limit <= 0 时使用 DefaultPageSize，最大 MaxPageSize
+func (r *Repository[T]) ListAfter(ctx context.Context, filter *Filter, cursor string, limit int, desc bool) (*CursorPage[T], error) {
+	limit = clampPageSize(limit)
+	tx, err := r.query(ctx, filter, []SortField{{Column: r.pk.DBName, Desc: desc}})
+	if err != nil {
+		return nil, err
+	}
+	if cursor != "" {
+		last, err := r.decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		col := clause.Column{Table: clause.CurrentTable, Name: r.pk.DBName}
+		if desc {
+			tx = tx.Where(clause.Lt{Column: col, Value: last})
+		} else {
+			tx = tx.Where(clause.Gt{Column: col, Value: last})
+		}
+	}
+
+	items := make([]T, 0, limit+1)
+	if err := tx.Limit(limit + 1).Find(&items).Error; err != nil { // 多查一条判断是否还有下一页
+		return nil, mapError("list after", err)
+	}
+	result := &CursorPage[T]{Items: items}
+	if len(items) > limit {
+		result.Items = items[:limit]
+		result.HasMore = true
+		next, err := r.encodeCursor(ctx, &result.Items[limit-1])
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = next
+	}
+	return result, nil
+}
+
+// query 构造带过滤条件和排序的查询，列名不在白名单中时返回 ErrColumnNotAllowed
+func (r *Repository[T]) query(ctx context.Context, filter *Filter, sort []SortField) (*gorm.DB, error) {
+	tx := r.db.WithContext(ctx).Model(new(T))
+	if filter != nil && len(filter.conds) > 0 {
+		exprs := make([]clause.Expression, 0, len(filter.conds))
+		for _, c := range filter.conds {
+			if err := r.checkColumn(c.column); err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, c.build(clause.Column{Table: clause.CurrentTable, Name: c.column}))
+		}
+		tx = tx.Where(clause.And(exprs...))
+	}
+	for _, s := range sort {
+		if err := r.checkColumn(s.Column); err != nil {
+			return nil, err
+		}
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: s.Column}, Desc: s.Desc})
+	}
+	return tx, nil
+}
+
+// checkColumn 校验列名是否在白名单中 (主键列始终允许)
+func (r *Repository[T]) checkColumn(column string) error {
+	if column == r.pk.DBName {
+		return nil
+	}
+	if _, ok := r.allowed[column]; !ok {
+		return fmt.Errorf("%w: %s", ErrColumnNotAllowed, column)
+	}
+	return nil
+}
+
+// pkEq 返回主键等于 id 的条件
+func (r *Repository[T]) pkEq(id any) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: r.pk.DBName}, Value: id}
+}
+
+// deletedAtField 返回模型的软删除字段，没有时返回 nil
+func (r *Repository[T]) deletedAtField() *schema.Field {
+	for _, f := range r.schema.Fields {
+		if f.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return f
+		}
+	}
+	return nil
+}
+
+// encodeCursor 把记录的主键值编码为不透明的游标
+func (r *Repository[T]) encodeCursor(ctx context.Context, item *T) (string, error) {
+	val, _ := r.pk.ValueOf(ctx, reflect.ValueOf(item).Elem())
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", fmt.Errorf("编码分页游标失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor 把游标解码为主键类型的值
+func (r *Repository[T]) decodeCursor(cursor string) (any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	val := reflect.New(r.pk.FieldType)
+	if err := json.Unmarshal(data, val.Interface()); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return val.Elem().Interface(), nil
+}
+
+// clampPageSize 把每页数量限制在 [1, MaxPageSize]，<= 0 时使用默认值
+func clampPageSize(size int) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	if size > MaxPageSize {
+		return MaxPageSize
+	}
+	return size
+}
+
+// mapError 统一仓储返回的错误: gorm.ErrRecordNotFound 映射为 commonerrors.ErrRepoNotFound，其余错误附带操作名
+func mapError(op string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return commonerrors.ErrRepoNotFound
+	case errors.Is(err, commonerrors.ErrVersionConflict):
+		return commonerrors.ErrVersionConflict
+	default:
+		return fmt.Errorf("repository %s: %w", op, err)
+	}
+}
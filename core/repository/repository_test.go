@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core/idgen"
+	"github.com/Xushengqwer/go-common/models/entities"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type article struct {
+	entities.BaseModel
+	AuthorID uint
+	Title    string
+	Secret   string
+}
+
+type versionedArticle struct {
+	entities.AuditedModel
+	Title string
+}
+
+// openTestDB 打开一个独立的内存 SQLite 数据库并建好测试表
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.AutoMigrate(&article{}, &versionedArticle{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return db
+}
+
+func newArticleRepo(t *testing.T, db *gorm.DB, opts ...Option) *Repository[article] {
+	t.Helper()
+	repo, err := New[article](db, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return repo
+}
+
+// seed 创建 n 篇文章，标题为 a1..an，AuthorID 交替为 1、2
+func seed(t *testing.T, repo *Repository[article], n int) []article {
+	t.Helper()
+	items := make([]article, n)
+	for i := range items {
+		items[i] = article{AuthorID: uint(i%2 + 1), Title: fmt.Sprintf("a%d", i+1)}
+		if err := repo.Create(context.Background(), &items[i]); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	return items
+}
+
+func TestRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := newArticleRepo(t, openTestDB(t))
+	a := seed(t, repo, 1)[0]
+
+	got, err := repo.Get(ctx, a.ID)
+	if err != nil || got.Title != "a1" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	got.Title = "edited"
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := repo.Get(ctx, a.ID); got.Title != "edited" {
+		t.Errorf("更新后的标题 = %q", got.Title)
+	}
+
+	if err := repo.Delete(ctx, a.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.Get(ctx, a.ID); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("软删除后 Get 应返回 ErrRepoNotFound，实际 %v", err)
+	}
+	if err := repo.Delete(ctx, a.ID); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("重复删除应返回 ErrRepoNotFound，实际 %v", err)
+	}
+	if err := repo.Restore(ctx, a.ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if err := repo.Restore(ctx, a.ID); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("恢复未删除的记录应返回 ErrRepoNotFound，实际 %v", err)
+	}
+	if err := repo.HardDelete(ctx, a.ID); err != nil {
+		t.Fatalf("HardDelete: %v", err)
+	}
+	if err := repo.Restore(ctx, a.ID); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("物理删除后恢复应返回 ErrRepoNotFound，实际 %v", err)
+	}
+}
+
+func TestRepositoryUpdateNotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := newArticleRepo(t, openTestDB(t))
+	a := seed(t, repo, 1)[0]
+
+	missing := article{Title: "missing"}
+	missing.ID = a.ID + 100
+	if err := repo.Update(ctx, &missing); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("更新不存在的记录应返回 ErrRepoNotFound，实际 %v", err)
+	}
+
+	if err := repo.Delete(ctx, a.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Update(ctx, &a); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("更新已软删除的记录应返回 ErrRepoNotFound，实际 %v", err)
+	}
+}
+
+func TestRepositoryUpdateUnchangedRow(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	// 模拟 MySQL 的行为: 值没有变化的行不计入 RowsAffected
+	err := db.Callback().Update().After("gorm:update").Register("test:unchanged_rows", func(tx *gorm.DB) {
+		tx.RowsAffected = 0
+	})
+	if err != nil {
+		t.Fatalf("注册回调失败: %v", err)
+	}
+	repo := newArticleRepo(t, db)
+	a := seed(t, repo, 1)[0]
+
+	if err := repo.Update(ctx, &a); err != nil {
+		t.Errorf("记录存在但没有影响任何行时 Update 不应返回错误，实际 %v", err)
+	}
+	missing := article{Title: "missing"}
+	missing.ID = a.ID + 100
+	if err := repo.Update(ctx, &missing); !errors.Is(err, commonerrors.ErrRepoNotFound) {
+		t.Errorf("记录不存在时仍应返回 ErrRepoNotFound，实际 %v", err)
+	}
+}
+
+func TestRepositoryUpdateVersionConflict(t *testing.T) {
+	if err := idgen.Init(config.IDGenConfig{NodeID: 1}); err != nil {
+		t.Fatalf("idgen.Init: %v", err)
+	}
+	ctx := context.Background()
+	repo, err := New[versionedArticle](openTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	a := versionedArticle{Title: "v1"}
+	if err := repo.Create(ctx, &a); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	stale := a
+
+	a.Title = "v2"
+	if err := repo.Update(ctx, &a); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	stale.Title = "stale"
+	if err := repo.Update(ctx, &stale); !errors.Is(err, commonerrors.ErrVersionConflict) {
+		t.Errorf("过期版本的更新应返回 ErrVersionConflict，实际 %v", err)
+	}
+}
+
+// TestRepositoryUpdatePreservesCreateOnlyFields Update 不覆盖 CreatedAt / CreatedBy 和软删除字段
+func TestRepositoryUpdatePreservesCreateOnlyFields(t *testing.T) {
+	if err := idgen.Init(config.IDGenConfig{NodeID: 1}); err != nil {
+		t.Fatalf("idgen.Init: %v", err)
+	}
+	repo, err := New[versionedArticle](openTestDB(t))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	a := versionedArticle{Title: "v1"}
+	if err := repo.Create(entities.WithActor(context.Background(), "u-1"), &a); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 模拟由请求体构造的实体: 只有主键、版本号和业务字段
+	edited := versionedArticle{Title: "v2"}
+	edited.ID, edited.Version = a.ID, a.Version
+	edited.DeletedAt = gorm.DeletedAt{Time: a.CreatedAt, Valid: true}
+	ctx := entities.WithActor(context.Background(), "u-2")
+	if err := repo.Update(ctx, &edited); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := repo.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("更新不应软删除记录，Get 返回 %v", err)
+	}
+	if got.Title != "v2" || got.CreatedBy != "u-1" || got.UpdatedBy != "u-2" {
+		t.Errorf("更新后 Title=%q CreatedBy=%q UpdatedBy=%q，CreatedBy 应保持 u-1", got.Title, got.CreatedBy, got.UpdatedBy)
+	}
+	if !got.CreatedAt.Equal(a.CreatedAt) {
+		t.Errorf("CreatedAt 不应被覆盖: %v -> %v", a.CreatedAt, got.CreatedAt)
+	}
+}
+
+func TestRepositoryAllowedColumns(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := newArticleRepo(t, db)
+	seed(t, repo, 2)
+
+	if _, err := repo.List(ctx, NewFilter().Eq("secret", "x")); !errors.Is(err, ErrColumnNotAllowed) {
+		t.Errorf("默认白名单为空，按普通列过滤应返回 ErrColumnNotAllowed，实际 %v", err)
+	}
+	if _, err := repo.List(ctx, nil, Desc("title")); !errors.Is(err, ErrColumnNotAllowed) {
+		t.Errorf("默认白名单为空，按普通列排序应返回 ErrColumnNotAllowed，实际 %v", err)
+	}
+	if items, err := repo.List(ctx, nil, Desc("id")); err != nil || len(items) != 2 || items[0].Title != "a2" {
+		t.Errorf("主键列始终允许排序，实际 %+v, %v", items, err)
+	}
+
+	repo = newArticleRepo(t, db, WithAllowedColumns("author_id", "title"))
+	items, err := repo.List(ctx, NewFilter().Eq("author_id", 1), Asc("title"))
+	if err != nil || len(items) != 1 || items[0].Title != "a1" {
+		t.Errorf("白名单中的列应允许过滤和排序，实际 %+v, %v", items, err)
+	}
+	if _, err := repo.List(ctx, NewFilter().Eq("secret", "x")); !errors.Is(err, ErrColumnNotAllowed) {
+		t.Errorf("白名单之外的列应返回 ErrColumnNotAllowed，实际 %v", err)
+	}
+	if _, err := New[article](db, WithAllowedColumns("missing")); err == nil {
+		t.Error("白名单中包含不存在的列时 New 应返回错误")
+	}
+}
+
+func TestRepositoryListPage(t *testing.T) {
+	ctx := context.Background()
+	repo := newArticleRepo(t, openTestDB(t), WithAllowedColumns("author_id"))
+	seed(t, repo, 5)
+
+	page, err := repo.ListPage(ctx, NewFilter().Eq("author_id", 1), 2, 2)
+	if err != nil {
+		t.Fatalf("ListPage: %v", err)
+	}
+	if page.Total != 3 || len(page.Items) != 1 || page.Items[0].Title != "a5" {
+		t.Errorf("第 2 页 = %+v", page)
+	}
+	empty, err := repo.ListPage(ctx, NewFilter().Eq("author_id", 9), 1, 0)
+	if err != nil || empty.Total != 0 || empty.Items == nil || empty.PageSize != DefaultPageSize {
+		t.Errorf("空结果 = %+v, %v", empty, err)
+	}
+}
+
+func TestRepositoryListAfter(t *testing.T) {
+	ctx := context.Background()
+	repo := newArticleRepo(t, openTestDB(t))
+	seed(t, repo, 5)
+
+	var titles []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("游标分页没有结束")
+		}
+		page, err := repo.ListAfter(ctx, nil, cursor, 2, true)
+		if err != nil {
+			t.Fatalf("ListAfter: %v", err)
+		}
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		if !page.HasMore {
+			if page.NextCursor != "" {
+				t.Error("没有更多数据时 NextCursor 应为空")
+			}
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if got := strings.Join(titles, ","); got != "a5,a4,a3,a2,a1" {
+		t.Errorf("降序游标分页结果 = %s", got)
+	}
+
+	if _, err := repo.ListAfter(ctx, nil, "not-a-cursor!", 2, false); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("无效游标应返回 ErrInvalidCursor，实际 %v", err)
+	}
+}
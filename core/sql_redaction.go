@@ -0,0 +1,324 @@
+package core
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Xushengqwer/go-common/config"
+)
+
+// redactedValue 是被脱敏的 SQL 参数在日志中的替代值
+const redactedValue = "***"
+
+var (
+	// sqlTableRe 匹配语句引用的表名 (主表和 JOIN 的表)
+	sqlTableRe = regexp.MustCompile(`(?i)\b(?:INSERT\s+INTO|UPDATE|FROM|JOIN)\s+([\w.]+)`)
+	// sqlInsertColumnsRe 匹配 INSERT 语句的列列表
+	sqlInsertColumnsRe = regexp.MustCompile(`(?i)INSERT\s+INTO\s+[\w.]+\s*\(([^)]*)\)\s*VALUES`)
+	// sqlInsertTailRe 匹配 INSERT ... VALUES 之后不属于 VALUES 列表的部分
+	sqlInsertTailRe = regexp.MustCompile(`(?i)\b(?:ON\s+CONFLICT|ON\s+DUPLICATE\s+KEY|RETURNING)\b`)
+	// sqlPlaceholderRe 匹配占位符: MySQL/SQLite 的 "?" 与 PostgreSQL 的 "$n"
+	sqlPlaceholderRe = regexp.MustCompile(`\?|\$\d+`)
+	// sqlColumnBeforeRe 匹配占位符之前的 "列名 操作符" (支持 IN (?, ?) 列表)
+	sqlColumnBeforeRe = regexp.MustCompile(`(?i)([\w.]+)\s*(?:=|<>|!=|>=|<=|>|<|\bLIKE|\bIN)\s*\(?[\s?,$\d]*$`)
+)
+
+// sqlRedactor 根据 GormLogConfig 对 SQL 参数脱敏、对语句截断
+// 由 GormLogger 与 GormTracingPlugin 共用，保证日志和链路中看到的 SQL 一致
+type sqlRedactor struct {
+	denyColumns map[string]map[string]struct{} // 表名 -> 列名集合，"*" 对所有表生效
+	maxLength   int
+}
+
+// newSQLRedactor 根据配置创建 sqlRedactor，表名和列名统一按小写匹配
+func newSQLRedactor(cfg config.GormLogConfig) *sqlRedactor {
+	r := &sqlRedactor{maxLength: cfg.MaxStatementLength}
+	if len(cfg.RedactColumns) > 0 {
+		r.denyColumns = make(map[string]map[string]struct{}, len(cfg.RedactColumns))
+		for table, cols := range cfg.RedactColumns {
+			set := make(map[string]struct{}, len(cols))
+			for _, c := range cols {
+				set[strings.ToLower(c)] = struct{}{}
+			}
+			r.denyColumns[strings.ToLower(table)] = set
+		}
+	}
+	return r
+}
+
+// enabled 判断是否配置了需要脱敏的列
+func (r *sqlRedactor) enabled() bool {
+	return len(r.denyColumns) > 0
+}
+
+// redactVars 返回脱敏后的参数列表；没有需要脱敏的参数时原样返回
+// 通过解析带占位符的 SQL 推断每个参数对应的列 (INSERT 的列列表，或 WHERE/SET 中的 "列 = ?"、"列 IN (?, ?)")。
+// 无法推断列名的参数 (例如函数参数、BETWEEN、LIMIT) 只要语句涉及配置了脱敏列的表就一律脱敏，宁可多脱敏也不泄露。
+func (r *sqlRedactor) redactVars(sql string, vars []interface{}) []interface{} {
+	if !r.enabled() || len(vars) == 0 {
+		return vars
+	}
+	tables, columns := placeholderColumns(sql, len(vars))
+	redacted, copied := vars, false
+	for i, candidates := range columns {
+		if !r.shouldRedact(tables, candidates) {
+			continue
+		}
+		if !copied {
+			redacted, copied = append([]interface{}(nil), vars...), true // 复制一份，避免修改 GORM 的 Statement.Vars
+		}
+		redacted[i] = redactedValue
+	}
+	return redacted
+}
+
+// shouldRedact 判断一个参数是否需要脱敏，candidates 是该参数在语句中出现的每个位置推断出的列
+func (r *sqlRedactor) shouldRedact(tables []string, candidates []sqlColumn) bool {
+	if len(candidates) == 0 {
+		// 参数没有对应的占位符，无法判断
+		return r.mayDeny(tables)
+	}
+	for _, col := range candidates {
+		if col.name == "" {
+			if r.mayDeny(tables) {
+				return true
+			}
+			continue
+		}
+		if col.table != "" && r.denied(col.table, col.name) {
+			return true
+		}
+		// 限定名可能是别名 (e.g., "u.phone")，因此同时检查语句引用的每张表
+		for _, table := range tables {
+			if r.denied(table, col.name) {
+				return true
+			}
+		}
+		if r.denied("*", col.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayDeny 判断语句涉及的表中是否可能存在需要脱敏的列 (无法识别表名时按可能存在处理)
+func (r *sqlRedactor) mayDeny(tables []string) bool {
+	if _, ok := r.denyColumns["*"]; ok || len(tables) == 0 {
+		return true
+	}
+	for _, table := range tables {
+		if _, ok := r.denyColumns[strings.ToLower(table)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// denied 判断某张表的某一列是否需要脱敏
+func (r *sqlRedactor) denied(table, column string) bool {
+	if set, ok := r.denyColumns[strings.ToLower(table)]; ok {
+		if _, ok := set[strings.ToLower(column)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// truncate 把超过 maxLength 的语句截断，并追加被截掉的字节数标记
+func (r *sqlRedactor) truncate(sql string) string {
+	if r.maxLength <= 0 || len(sql) <= r.maxLength {
+		return sql
+	}
+	cut := r.maxLength
+	for cut > 0 && !utf8.RuneStart(sql[cut]) { // 不在多字节字符中间截断
+		cut--
+	}
+	return fmt.Sprintf("%s...[truncated %d bytes]", sql[:cut], len(sql)-cut)
+}
+
+// sqlColumn 是占位符对应的列，table 为列名的限定前缀 (可能是别名)，name 为空表示无法推断
+type sqlColumn struct {
+	table string
+	name  string
+}
+
+// placeholderColumns 返回语句引用的表名，以及每个参数 (按下标) 在语句中各个出现位置推断出的列
+func placeholderColumns(sql string, n int) ([]string, [][]sqlColumn) {
+	normalized := strings.NewReplacer("`", "", `"`, "").Replace(sql)
+	columns := make([][]sqlColumn, n)
+
+	var tables []string
+	for _, m := range sqlTableRe.FindAllStringSubmatch(normalized, -1) {
+		tables = append(tables, lastSegment(m[1]))
+	}
+
+	var insertCols []string
+	valuesAt, valuesEnd := -1, len(normalized)
+	if loc := sqlInsertColumnsRe.FindStringSubmatchIndex(normalized); loc != nil {
+		for _, c := range strings.Split(normalized[loc[2]:loc[3]], ",") {
+			insertCols = append(insertCols, lastSegment(strings.TrimSpace(c)))
+		}
+		valuesAt = loc[1]
+		if tail := sqlInsertTailRe.FindStringIndex(normalized[valuesAt:]); tail != nil {
+			valuesEnd = valuesAt + tail[0]
+		}
+	}
+
+	seq := 0 // "?" 占位符的顺序下标
+	valueSeq := 0
+	for _, loc := range sqlPlaceholderRe.FindAllStringIndex(normalized, -1) {
+		idx := seq
+		if normalized[loc[0]] == '$' {
+			num, err := strconv.Atoi(normalized[loc[0]+1 : loc[1]])
+			if err != nil {
+				continue
+			}
+			idx = num - 1
+		} else {
+			seq++
+		}
+		if idx < 0 || idx >= n {
+			continue
+		}
+
+		// INSERT ... VALUES (?, ?), (?, ?): 按列列表循环对应
+		if valuesAt >= 0 && loc[0] >= valuesAt && loc[0] < valuesEnd && len(insertCols) > 0 {
+			columns[idx] = append(columns[idx], sqlColumn{name: insertCols[valueSeq%len(insertCols)]})
+			valueSeq++
+			continue
+		}
+		// 其余情况: 向前查看占位符前的 "列名 操作符"
+		start := loc[0] - 128
+		if start < 0 {
+			start = 0
+		}
+		var col sqlColumn
+		if m := sqlColumnBeforeRe.FindStringSubmatch(normalized[start:loc[0]]); m != nil {
+			col.name = lastSegment(m[1])
+			if i := strings.LastIndexByte(m[1], '.'); i >= 0 {
+				col.table = lastSegment(m[1][:i])
+			}
+		}
+		columns[idx] = append(columns[idx], col)
+	}
+	return tables, columns
+}
+
+// lastSegment 返回 "schema.table" 或 "table.column" 形式中的最后一段
+func lastSegment(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// paramsCapture 记录一次 Logger.Trace 调用 fc() 期间 ParamsFilter 的结果
+type paramsCapture struct {
+	filtered bool     // ParamsFilter 是否被调用；db.Scan 等经过 logger.Recorder 的调用不会调用 ParamsFilter
+	args     []string // ParameterizedQueries + LogQueryArgs 模式下脱敏后的参数
+}
+
+// paramsChannel 是 GormLogger.Trace 与 ParamsFilter 之间的旁路通道
+// GORM 在 Trace 内部调用 fc()，fc() 再以同一个 Statement.Context 同步调用 ParamsFilter，
+// 因此按 context 登记当前的 paramsCapture 即可把结果交回 Trace；同一个 context 上并发的 Trace 依次调用 fc()。
+type paramsChannel struct {
+	mu    sync.Mutex
+	slots map[context.Context]*paramsSlot
+}
+
+// paramsSlot 是一个 context 上正在进行的 fc() 调用
+type paramsSlot struct {
+	mu      sync.Mutex // 同一时刻只有一个 Trace 在该 context 上调用 fc()
+	refs    int
+	capture *paramsCapture
+}
+
+func newParamsChannel() *paramsChannel {
+	return &paramsChannel{slots: make(map[context.Context]*paramsSlot)}
+}
+
+// call 调用 fc()，返回其结果以及期间 ParamsFilter 写入的 paramsCapture
+func (c *paramsChannel) call(ctx context.Context, fc func() (string, int64)) (string, int64, paramsCapture) {
+	var capture paramsCapture
+	// context 作为 map 的键必须可比较 (自定义的值类型 context 可能不可比较)，否则无法登记，按未经过 ParamsFilter 处理
+	if ctx == nil || !reflect.TypeOf(ctx).Comparable() {
+		sql, rows := fc()
+		return sql, rows, capture
+	}
+
+	c.mu.Lock()
+	slot, ok := c.slots[ctx]
+	if !ok {
+		slot = &paramsSlot{}
+		c.slots[ctx] = slot
+	}
+	slot.refs++
+	c.mu.Unlock()
+
+	slot.mu.Lock()
+	slot.capture = &capture
+	sql, rows := fc()
+	slot.capture = nil
+	slot.mu.Unlock()
+
+	c.mu.Lock()
+	if slot.refs--; slot.refs == 0 {
+		delete(c.slots, ctx)
+	}
+	c.mu.Unlock()
+	return sql, rows, capture
+}
+
+// current 返回 ctx 上正在进行的 fc() 调用的 paramsCapture，不在 Trace 中调用时返回 nil
+// 只能由 fc() 中的 ParamsFilter 调用: 此时当前 goroutine 持有 slot.mu，读取 slot.capture 是安全的
+func (c *paramsChannel) current(ctx context.Context) *paramsCapture {
+	if ctx == nil || !reflect.TypeOf(ctx).Comparable() {
+		return nil
+	}
+	c.mu.Lock()
+	slot := c.slots[ctx]
+	c.mu.Unlock()
+	if slot == nil {
+		return nil
+	}
+	return slot.capture
+}
+
+// formatArgs 把脱敏后的参数格式化为日志中的字符串列表
+func formatArgs(vars []interface{}) []string {
+	args := make([]string, len(vars))
+	for i, v := range vars {
+		args[i] = formatArg(v)
+	}
+	return args
+}
+
+// formatArg 把单个 SQL 参数格式化为日志中的字符串
+func formatArg(v interface{}) string {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "NULL"
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		if val, err := valuer.Value(); err == nil {
+			v = val
+		}
+	}
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if utf8.Valid(val) {
+			return string(val)
+		}
+		return "<binary>"
+	default:
+		return fmt.Sprint(val)
+	}
+}
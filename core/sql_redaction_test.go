@@ -0,0 +1,266 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+)
+
+// 测试中的敏感值，任何一个出现在日志中都表示泄露
+var sensitiveValues = []string{"13500000000", "13400000000", "s3cret-hash", "s3cret-hash-2"}
+
+type redactedUser struct {
+	ID           uint `gorm:"primaryKey"`
+	Name         string
+	Phone        string
+	PasswordHash string
+}
+
+func (redactedUser) TableName() string { return "users" }
+
+// newObservedDB 返回使用 GormLogger 的 SQLite 数据库，以及记录所有日志的 observer
+func newObservedDB(t *testing.T, cfg config.GormLogConfig) (*gorm.DB, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	cfg.Level = "info"
+	cfg.SkipCallerLookup = true
+	db := openTestDB(t, &gorm.Config{Logger: NewGormLogger(&ZapLogger{logger: zap.New(core)}, cfg)})
+	if err := db.AutoMigrate(&redactedUser{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	logs.TakeAll() // 丢弃建表产生的日志
+	return db, logs
+}
+
+// statements 返回日志中记录的语句和参数
+func statements(logs *observer.ObservedLogs) []string {
+	var out []string
+	for _, e := range logs.TakeAll() {
+		fields := e.ContextMap()
+		line, _ := fields[defaultGormFieldStatement].(string)
+		if args, ok := fields[defaultGormFieldArgs]; ok {
+			line += " args=" + strings.Join(toStrings(args), ",")
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func toStrings(v interface{}) []string {
+	items, _ := v.([]interface{})
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i], _ = item.(string)
+	}
+	return out
+}
+
+// assertNoLeak 检查日志中没有任何敏感值，并且至少有一条语句包含 want
+func assertNoLeak(t *testing.T, stmts []string, want string) {
+	t.Helper()
+	if len(stmts) == 0 {
+		t.Fatal("没有记录任何语句")
+	}
+	found := false
+	for _, stmt := range stmts {
+		for _, v := range sensitiveValues {
+			if strings.Contains(stmt, v) {
+				t.Errorf("日志中泄露了 %q: %s", v, stmt)
+			}
+		}
+		if strings.Contains(stmt, want) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("日志中没有包含 %q 的语句: %v", want, stmts)
+	}
+}
+
+var redactionModes = []struct {
+	name string
+	cfg  config.GormLogConfig
+}{
+	{"interpolated", config.GormLogConfig{}},
+	{"parameterized", config.GormLogConfig{ParameterizedQueries: true, LogQueryArgs: true}},
+}
+
+func TestGormLoggerRedactsDeniedColumns(t *testing.T) {
+	cases := []struct {
+		name string
+		run  func(db *gorm.DB)
+		want string // 确认语句确实被记录，且未脱敏的参数保持可读
+	}{
+		{"Insert", func(db *gorm.DB) {
+			db.Create(&redactedUser{Name: "alice", Phone: "13500000000", PasswordHash: "s3cret-hash"})
+		}, "alice"},
+		{"BatchInsert", func(db *gorm.DB) {
+			db.Create(&[]redactedUser{
+				{Name: "alice", Phone: "13500000000", PasswordHash: "s3cret-hash"},
+				{Name: "bob", Phone: "13400000000", PasswordHash: "s3cret-hash-2"},
+			})
+		}, "bob"},
+		{"UpdateSet", func(db *gorm.DB) {
+			db.Model(&redactedUser{ID: 1}).Updates(map[string]interface{}{"name": "carol", "phone": "13500000000"})
+		}, "carol"},
+		{"Where", func(db *gorm.DB) {
+			var users []redactedUser
+			db.Where("name = ? AND phone = ?", "dave", "13500000000").Find(&users)
+		}, "dave"},
+		{"StructCondition", func(db *gorm.DB) {
+			var users []redactedUser
+			db.Where(&redactedUser{Name: "erin", PasswordHash: "s3cret-hash"}).Find(&users)
+		}, "erin"},
+		{"QualifiedColumn", func(db *gorm.DB) {
+			var users []redactedUser
+			db.Table("users AS u").Where("u.phone = ? AND u.name = ?", "13500000000", "frank").Find(&users)
+		}, "frank"},
+		{"WhereIn", func(db *gorm.DB) {
+			var users []redactedUser
+			db.Where("phone IN ?", []string{"13500000000", "13400000000"}).Find(&users)
+		}, "phone IN"},
+		{"MapIn", func(db *gorm.DB) {
+			var users []redactedUser
+			db.Where(map[string]interface{}{"phone": []string{"13500000000", "13400000000"}}).Find(&users)
+		}, "IN"},
+		{"RawFindIn", func(db *gorm.DB) {
+			var users []redactedUser
+			db.Raw("SELECT * FROM users WHERE phone IN (?)", []string{"13500000000", "13400000000"}).Find(&users)
+		}, "phone IN"},
+		{"RawScanIn", func(db *gorm.DB) {
+			// db.Scan 经过 logger.Recorder，不会调用 ParamsFilter
+			var users []redactedUser
+			db.Raw("SELECT * FROM users WHERE phone IN (?)", []string{"13500000000", "13400000000"}).Scan(&users)
+		}, "phone IN"},
+		{"RawRow", func(db *gorm.DB) {
+			var n int
+			_ = db.Raw("SELECT COUNT(*) FROM users WHERE password_hash = ?", "s3cret-hash").Row().Scan(&n)
+		}, "password_hash"},
+		{"Exec", func(db *gorm.DB) {
+			db.Exec("UPDATE users SET password_hash = ?, name = ? WHERE phone = ?", "s3cret-hash", "grace", "13500000000")
+		}, "grace"},
+		{"UnknownColumn", func(db *gorm.DB) {
+			// 函数参数无法推断列名，涉及配置了脱敏列的表时按敏感值处理
+			var users []redactedUser
+			db.Where("phone = LOWER(?)", "13500000000").Find(&users)
+		}, "LOWER"},
+	}
+
+	for _, mode := range redactionModes {
+		t.Run(mode.name, func(t *testing.T) {
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					cfg := mode.cfg
+					cfg.RedactColumns = map[string][]string{"users": {"password_hash", "phone"}}
+					db, logs := newObservedDB(t, cfg)
+					tc.run(db)
+					assertNoLeak(t, statements(logs), tc.want)
+				})
+			}
+		})
+	}
+}
+
+func TestGormLoggerKeepsOtherTablesReadable(t *testing.T) {
+	db, logs := newObservedDB(t, config.GormLogConfig{
+		RedactColumns: map[string][]string{"accounts": {"phone"}},
+	})
+	var users []redactedUser
+	db.Where("phone = ?", "13500000000").Find(&users)
+
+	if stmts := statements(logs); len(stmts) != 1 || !strings.Contains(stmts[0], "13500000000") {
+		t.Errorf("其他表的同名列不应被脱敏: %v", stmts)
+	}
+}
+
+func TestGormLoggerWildcardTable(t *testing.T) {
+	db, logs := newObservedDB(t, config.GormLogConfig{
+		RedactColumns: map[string][]string{"*": {"phone"}},
+	})
+	db.Create(&redactedUser{Name: "alice", Phone: "13500000000"})
+	assertNoLeak(t, statements(logs), "alice")
+}
+
+func TestGormLoggerParameterizedArgs(t *testing.T) {
+	db, logs := newObservedDB(t, config.GormLogConfig{
+		ParameterizedQueries: true,
+		LogQueryArgs:         true,
+		RedactColumns:        map[string][]string{"users": {"phone"}},
+	})
+	var users []redactedUser
+	db.Where("name = ? AND phone = ?", "alice", "13500000000").Find(&users)
+
+	stmts := statements(logs)
+	if len(stmts) != 1 {
+		t.Fatalf("期望 1 条语句，实际 %v", stmts)
+	}
+	if want := "name = ? AND phone = ? args=alice," + redactedValue; !strings.Contains(stmts[0], want) {
+		t.Errorf("语句应保留占位符并单独记录脱敏后的参数，实际 %q", stmts[0])
+	}
+	if strings.ContainsRune(stmts[0], 0) {
+		t.Errorf("语句中不应附带编码后的参数: %q", stmts[0])
+	}
+}
+
+func TestGormLoggerParameterizedWithoutArgs(t *testing.T) {
+	db, logs := newObservedDB(t, config.GormLogConfig{ParameterizedQueries: true})
+	var users []redactedUser
+	db.Where("phone = ?", "13500000000").Find(&users)
+
+	stmts := statements(logs)
+	if len(stmts) != 1 || strings.Contains(stmts[0], "13500000000") || strings.Contains(stmts[0], "args=") {
+		t.Errorf("未开启 LogQueryArgs 时只记录占位符: %v", stmts)
+	}
+}
+
+func TestGormLoggerTruncation(t *testing.T) {
+	db, logs := newObservedDB(t, config.GormLogConfig{MaxStatementLength: 40})
+	var users []redactedUser
+	db.Where("name = ?", strings.Repeat("x", 100)).Find(&users)
+
+	stmts := statements(logs)
+	if len(stmts) != 1 {
+		t.Fatalf("期望 1 条语句，实际 %v", stmts)
+	}
+	head, marker, ok := strings.Cut(stmts[0], "...[truncated ")
+	if !ok || len(head) != 40 || !strings.HasSuffix(marker, " bytes]") {
+		t.Errorf("语句应截断为 40 字节并追加标记，实际 %q", stmts[0])
+	}
+}
+
+func TestSQLRedactorTruncateRuneBoundary(t *testing.T) {
+	r := newSQLRedactor(config.GormLogConfig{MaxStatementLength: 4})
+	if got := r.truncate("ab中文"); got != "ab...[truncated 6 bytes]" {
+		t.Errorf("不应在多字节字符中间截断，实际 %q", got)
+	}
+	if got := r.truncate("abcd"); got != "abcd" {
+		t.Errorf("未超过上限的语句不应截断，实际 %q", got)
+	}
+}
+
+func TestSQLRedactorPostgresPlaceholders(t *testing.T) {
+	r := newSQLRedactor(config.GormLogConfig{RedactColumns: map[string][]string{"users": {"phone"}}})
+	vars := []interface{}{"alice", "13500000000"}
+	got := r.redactVars(`SELECT * FROM "users" WHERE "name" = $1 AND "phone" = $2`, vars)
+	if got[0] != "alice" || got[1] != redactedValue {
+		t.Errorf("redactVars = %v", got)
+	}
+	if vars[1] != "13500000000" {
+		t.Error("redactVars 不应修改传入的参数")
+	}
+}
+
+func TestSQLRedactorInsertUpsert(t *testing.T) {
+	r := newSQLRedactor(config.GormLogConfig{RedactColumns: map[string][]string{"users": {"phone"}}})
+	got := r.redactVars("INSERT INTO users (name,phone) VALUES (?,?) ON CONFLICT (id) DO UPDATE SET name = ?",
+		[]interface{}{"alice", "13500000000", "bob"})
+	if got[0] != "alice" || got[1] != redactedValue || got[2] != "bob" {
+		t.Errorf("VALUES 之后的占位符应按列名推断，实际 %v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// newExporter 根据 TracerConfig 创建 SpanExporter
+// ExporterType 为 "multi" 时按 cfg.Exporters 逐个创建，并组合为扇出的 multiExporter
+func newExporter(ctx context.Context, cfg config.TracerConfig) (sdktrace.SpanExporter, error) {
+	if cfg.ExporterType != "multi" {
+		return newSingleExporter(ctx, cfg.ExporterType, cfg.ExporterEndpoint, cfg)
+	}
+
+	if len(cfg.Exporters) == 0 {
+		return nil, errors.New("multi 模式下 exporters 不能为空")
+	}
+	exporters := make(multiExporter, 0, len(cfg.Exporters))
+	for _, ec := range cfg.Exporters {
+		exp, err := newSingleExporter(ctx, ec.Type, ec.Endpoint, cfg)
+		if err != nil {
+			// 关闭已创建的 Exporter，避免泄漏连接或文件句柄
+			_ = exporters.Shutdown(ctx)
+			return nil, fmt.Errorf("创建 %s exporter 失败: %w", ec.Type, err)
+		}
+		exporters = append(exporters, exp)
+	}
+	return exporters, nil
+}
+
+// newSingleExporter 创建单个 SpanExporter
+func newSingleExporter(ctx context.Context, exporterType, endpoint string, cfg config.TracerConfig) (sdktrace.SpanExporter, error) {
+	switch exporterType {
+	case "otlp_grpc":
+		// 注意: 生产环境通常需要配置 TLS, headers (API Key) 等
+		opts := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(endpoint),
+			//  todo 注意：生产环境通常需要加密传输 (TLS)！这里 WithInsecure 是为了方便测试不加密
+			otlptracegrpc.WithInsecure(),
+			otlptracegrpc.WithTimeout(5 * time.Second),
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case "otlp_http":
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(endpoint),
+			otlptracehttp.WithInsecure(), // 生产环境应移除或配置 TLS
+			// otlptracehttp.WithURLPath("/v1/traces"), // 有些 OTLP 接收端需要特定的 URL 路径
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "zipkin":
+		// endpoint 需要是完整的 URL，例如 "http://zipkin:9411/api/v2/spans"
+		// Jaeger 官方 exporter 已被 OTel 移除，Jaeger 后端请使用 otlp_grpc / otlp_http
+		return zipkin.New(endpoint)
+	case "file":
+		return newFileExporter(cfg.File)
+	case "multi":
+		return nil, errors.New("multi 模式不能嵌套")
+	default:
+		return nil, fmt.Errorf("不支持的 exporter 类型: %s", exporterType)
+	}
+}
+
+// multiExporter 把同一批 Span 并发扇出到多个 Exporter
+// 单个 Exporter 失败不会影响其他 Exporter，所有错误会被合并后返回
+type multiExporter []sdktrace.SpanExporter
+
+var _ sdktrace.SpanExporter = multiExporter(nil)
+
+// ExportSpans 实现 sdktrace.SpanExporter 接口
+func (m multiExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return m.each(func(e sdktrace.SpanExporter) error {
+		return e.ExportSpans(ctx, spans)
+	})
+}
+
+// Shutdown 实现 sdktrace.SpanExporter 接口
+func (m multiExporter) Shutdown(ctx context.Context) error {
+	return m.each(func(e sdktrace.SpanExporter) error {
+		return e.Shutdown(ctx)
+	})
+}
+
+// each 并发地对每个 Exporter 执行 fn，并合并所有错误
+func (m multiExporter) each(fn func(sdktrace.SpanExporter) error) error {
+	errs := make([]error, len(m))
+	var wg sync.WaitGroup
+	for i, e := range m {
+		wg.Add(1)
+		go func(i int, e sdktrace.SpanExporter) {
+			defer wg.Done()
+			errs[i] = fn(e)
+		}(i, e)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
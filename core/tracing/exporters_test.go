@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// collector 是一个记录请求的本地 HTTP 采集端，用来代替 Zipkin / OTLP 后端
+type collector struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies map[string][][]byte // 请求路径 -> 请求体
+	status int
+}
+
+func newCollector(t *testing.T, status int) *collector {
+	t.Helper()
+	c := &collector{bodies: make(map[string][][]byte), status: status}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		c.bodies[r.URL.Path] = append(c.bodies[r.URL.Path], body)
+		c.mu.Unlock()
+		w.WriteHeader(c.status)
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *collector) requests(path string) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bodies[path]
+}
+
+// zipkinSpanNames 解析 Zipkin v2 JSON 请求体中的 Span 名称
+func zipkinSpanNames(t *testing.T, bodies [][]byte) []string {
+	t.Helper()
+	var names []string
+	for _, body := range bodies {
+		var spans []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(body, &spans); err != nil {
+			t.Fatalf("无效的 Zipkin 请求体: %v", err)
+		}
+		for _, s := range spans {
+			names = append(names, s.Name)
+		}
+	}
+	return names
+}
+
+func TestZipkinExporter(t *testing.T) {
+	zipkin := newCollector(t, http.StatusAccepted)
+	exp, err := newExporter(context.Background(), config.TracerConfig{
+		ExporterType:     "zipkin",
+		ExporterEndpoint: zipkin.URL + "/api/v2/spans",
+	})
+	if err != nil {
+		t.Fatalf("newExporter: %v", err)
+	}
+	exportSpans(t, exp, "GET /api/v1/posts")
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if got := zipkinSpanNames(t, zipkin.requests("/api/v2/spans")); len(got) != 1 || !strings.EqualFold(got[0], "GET /api/v1/posts") {
+		t.Errorf("Zipkin 收到的 Span = %v", got)
+	}
+}
+
+func TestMultiExporterFansOut(t *testing.T) {
+	zipkin := newCollector(t, http.StatusAccepted)
+	otlp := newCollector(t, http.StatusOK)
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+
+	exp, err := newExporter(context.Background(), config.TracerConfig{
+		ExporterType: "multi",
+		Exporters: []config.ExporterConfig{
+			{Type: "zipkin", Endpoint: zipkin.URL + "/api/v2/spans"},
+			{Type: "otlp_http", Endpoint: strings.TrimPrefix(otlp.URL, "http://")},
+			{Type: "file"},
+		},
+		File: config.FileExporterConfig{Path: path},
+	})
+	if err != nil {
+		t.Fatalf("newExporter: %v", err)
+	}
+	exportSpans(t, exp, "one", "two")
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if got := zipkinSpanNames(t, zipkin.requests("/api/v2/spans")); strings.Join(got, ",") != "one,two" {
+		t.Errorf("Zipkin 收到的 Span = %v", got)
+	}
+	if got := otlp.requests("/v1/traces"); len(got) != 2 {
+		t.Errorf("OTLP 应收到 2 次导出请求，实际 %d 次", len(got))
+	}
+	if got := readSpanNames(t, path); strings.Join(got, ",") != "one,two" {
+		t.Errorf("文件中的 Span = %v", got)
+	}
+}
+
+func TestMultiExporterIsolatesFailures(t *testing.T) {
+	zipkin := newCollector(t, http.StatusInternalServerError)
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+
+	exp, err := newExporter(context.Background(), config.TracerConfig{
+		ExporterType: "multi",
+		Exporters: []config.ExporterConfig{
+			{Type: "zipkin", Endpoint: zipkin.URL + "/api/v2/spans"},
+			{Type: "file"},
+		},
+		File: config.FileExporterConfig{Path: path},
+	})
+	if err != nil {
+		t.Fatalf("newExporter: %v", err)
+	}
+	defer exp.Shutdown(context.Background())
+
+	spans := tracetest.SpanStubs{{Name: "kept"}}.Snapshots()
+	if err := exp.ExportSpans(context.Background(), spans); err == nil {
+		t.Error("Zipkin 导出失败时应返回错误")
+	}
+	if got := readSpanNames(t, path); len(got) != 1 || got[0] != "kept" {
+		t.Errorf("一个 Exporter 失败不应影响其他 Exporter，文件中的 Span = %v", got)
+	}
+}
+
+func TestNewExporterErrors(t *testing.T) {
+	cases := []config.TracerConfig{
+		{ExporterType: "jaeger"},
+		{ExporterType: "multi"},
+		{ExporterType: "multi", Exporters: []config.ExporterConfig{{Type: "multi"}}},
+		{ExporterType: "multi", Exporters: []config.ExporterConfig{{Type: "stdout"}, {Type: "file"}}},
+	}
+	for _, cfg := range cases {
+		if exp, err := newExporter(context.Background(), cfg); err == nil {
+			_ = exp.Shutdown(context.Background())
+			t.Errorf("%+v 应返回错误", cfg)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultFileMaxSizeMB 是未配置 MaxSizeMB 时单个 Span 文件的最大大小
+const defaultFileMaxSizeMB = 100
+
+// rotatedTimeFormat 是轮转文件名中的时间戳格式 (<path>.<时间戳>)
+const rotatedTimeFormat = "20060102T150405.000"
+
+// fileExporter 把 Span 以 JSON Lines 格式 (每行一个 Span) 写入本地文件，用于离线分析
+// 编码复用 stdouttrace (不开启 PrettyPrint 时每个 Span 恰好是一行)，写入目标是可轮转的文件
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file *rotatingFile
+}
+
+// newFileExporter 根据配置创建文件 Exporter
+func newFileExporter(cfg config.FileExporterConfig) (sdktrace.SpanExporter, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("file exporter 需要配置 file.path")
+	}
+	maxSizeMB := cfg.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultFileMaxSizeMB
+	}
+
+	f, err := newRotatingFile(cfg.Path, int64(maxSizeMB)*1024*1024, cfg.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &fileExporter{SpanExporter: exp, file: f}, nil
+}
+
+// Shutdown 实现 sdktrace.SpanExporter 接口，停止编码后关闭文件
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.file.Close())
+}
+
+// rotatingFile 是一个按大小轮转的 io.Writer
+// 单次 Write 不会被拆分到两个文件中，因此每行 JSON 总是完整的
+// 轮转失败不会让文件永久不可用: 重命名失败时继续追加原文件，重新打开失败时下一次写入会再次尝试打开
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File // 为 nil 且 closed 为 false 表示上一次轮转没能打开新文件
+	size       int64
+	closed     bool
+}
+
+// newRotatingFile 打开 (或创建) 目标文件，必要时创建父目录
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建 span 文件目录失败: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write 实现 io.Writer 接口，写入前如果超过大小上限则先轮转
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close 关闭当前文件，之后的写入会返回 os.ErrClosed
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// open 以追加方式打开目标文件，并记录已有大小
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开 span 文件失败: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("读取 span 文件信息失败: %w", err)
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// rotate 把当前文件重命名为 <path>.<时间戳>，打开新文件，并清理超出数量的旧文件
+// 重命名或清理失败只通过 otel.Handle 上报，本次写入继续进行；返回错误时 r.file 为 nil，由下一次写入重新打开
+func (r *rotatingFile) rotate() error {
+	closeErr := r.file.Close()
+	r.file = nil
+	if closeErr != nil {
+		otel.Handle(fmt.Errorf("关闭 span 文件失败: %w", closeErr))
+	}
+	rotated := r.path + "." + time.Now().Format(rotatedTimeFormat)
+	// 同一毫秒内多次轮转时追加序号，避免覆盖上一个轮转文件
+	for i := 1; fileExists(rotated); i++ {
+		rotated = fmt.Sprintf("%s.%s-%d", r.path, time.Now().Format(rotatedTimeFormat), i)
+	}
+	if err := os.Rename(r.path, rotated); err != nil {
+		// 重新打开原文件继续追加 (文件会超过大小上限)，下一次写入时再尝试轮转
+		otel.Handle(fmt.Errorf("轮转 span 文件失败，继续写入原文件: %w", err))
+		return r.open()
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if err := r.prune(); err != nil {
+		otel.Handle(fmt.Errorf("清理旧的 span 文件失败: %w", err))
+	}
+	return nil
+}
+
+// prune 只保留最新的 maxBackups 个轮转文件 (时间戳格式保证按文件名排序即按时间排序)
+func (r *rotatingFile) prune() error {
+	if r.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return err
+	}
+	if len(backups) <= r.maxBackups {
+		return nil
+	}
+	sort.Strings(backups)
+	var errs []error
+	for _, old := range backups[:len(backups)-r.maxBackups] {
+		errs = append(errs, os.Remove(old))
+	}
+	return errors.Join(errs...)
+}
+
+// fileExists 判断路径是否已存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
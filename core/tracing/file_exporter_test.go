@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// exportSpans 通过同步导出的 TracerProvider 生成给定名称的 Span
+func exportSpans(t *testing.T, exp sdktrace.SpanExporter, names ...string) {
+	t.Helper()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	tr := tp.Tracer("test")
+	for _, name := range names {
+		_, span := tr.Start(context.Background(), name)
+		span.End()
+	}
+	// 只刷新、不关闭 Exporter，由调用方决定何时 Shutdown
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush: %v", err)
+	}
+}
+
+// readSpanNames 读取 JSON Lines 文件中每一行 Span 的名称
+func readSpanNames(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("打开 %s 失败: %v", path, err)
+	}
+	defer f.Close()
+
+	var names []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var span struct{ Name string }
+		if err := json.Unmarshal(sc.Bytes(), &span); err != nil {
+			t.Fatalf("%s 中存在不完整的 JSON 行: %v", path, err)
+		}
+		names = append(names, span.Name)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("读取 %s 失败: %v", path, err)
+	}
+	return names
+}
+
+func TestFileExporterWritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "spans.jsonl")
+	exp, err := newFileExporter(config.FileExporterConfig{Path: path})
+	if err != nil {
+		t.Fatalf("newFileExporter: %v", err)
+	}
+	exportSpans(t, exp, "a", "b", "c")
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if got := readSpanNames(t, path); strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("文件中的 Span = %v", got)
+	}
+}
+
+func TestFileExporterRequiresPath(t *testing.T) {
+	if _, err := newFileExporter(config.FileExporterConfig{}); err == nil {
+		t.Error("未配置 path 时应返回错误")
+	}
+}
+
+func TestRotatingFileRotatesAndPrunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	f, err := newRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err := f.Write([]byte("0123456789\n")); err != nil {
+			t.Fatalf("第 %d 次写入失败: %v", i, err)
+		}
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Errorf("应只保留 2 个轮转文件，实际 %v", backups)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "0123456789\n" {
+		t.Errorf("当前文件内容 = %q, %v", data, err)
+	}
+}
+
+func TestRotatingFileRecoversFromRenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	f, err := newRotatingFile(path, 12, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write([]byte("0123456789\n")); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	// 删除当前文件，使下一次轮转时的重命名失败
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := f.Write([]byte("after\n")); err != nil {
+			t.Fatalf("重命名失败后的第 %d 次写入失败: %v", i, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "after\nafter\n" {
+		t.Errorf("重命名失败后应重新打开原路径继续写入，实际 %q, %v", data, err)
+	}
+}
+
+func TestRotatingFileClose(t *testing.T) {
+	f, err := newRotatingFile(filepath.Join(t.TempDir(), "spans.jsonl"), 10, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := f.Write([]byte("x")); err != os.ErrClosed {
+		t.Errorf("关闭后写入应返回 os.ErrClosed，实际 %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"github.com/Xushengqwer/go-common/core"
+
+	"go.uber.org/zap"
+)
+
+// Option 定义 InitTracerProvider 的可选配置项
+type Option func(*options)
+
+// options 保存 InitTracerProvider 的可选配置
+type options struct {
+	logger         *core.ZapLogger
+	lenientSampler bool
+}
+
+// WithLogger 设置追踪初始化使用的日志记录器
+// 设置后，初始化过程的日志以及 OTel SDK 内部错误 (例如 Exporter 导出失败) 都会通过该 logger 以结构化形式输出
+func WithLogger(logger *core.ZapLogger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLenientSampler 开启宽松的采样配置校验 (旧行为)
+// 采样器类型未知或 SamplerParam 越界时不返回错误，而是记录警告并回退到 AlwaysSample。
+// 注意：这可能导致生产环境 100% 采样，仅建议在迁移期间使用。
+func WithLenientSampler() Option {
+	return func(o *options) {
+		o.lenientSampler = true
+	}
+}
+
+// newOptions 应用所有 Option 并返回最终配置
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// info 在设置了 logger 时记录 Info 日志
+func (o *options) info(msg string, fields ...zap.Field) {
+	if o.logger != nil {
+		o.logger.Info(msg, fields...)
+	}
+}
+
+// warn 在设置了 logger 时记录 Warn 日志
+func (o *options) warn(msg string, fields ...zap.Field) {
+	if o.logger != nil {
+		o.logger.Warn(msg, fields...)
+	}
+}
@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// deploymentEnvVar 是 ResourceConfig.Environment 为空时读取部署环境的环境变量
+const deploymentEnvVar = "DEPLOY_ENV"
+
+// k8sEnvVars 定义 K8s 属性与 Downward API 环境变量的对应关系
+// 每个属性按顺序尝试多个常见的变量名，取第一个非空值。Deployment 中的典型写法:
+//
+//	env:
+//	  - name: K8S_POD_NAME
+//	    valueFrom: { fieldRef: { fieldPath: metadata.name } }
+//	  - name: K8S_NAMESPACE_NAME
+//	    valueFrom: { fieldRef: { fieldPath: metadata.namespace } }
+//	  - name: K8S_NODE_NAME
+//	    valueFrom: { fieldRef: { fieldPath: spec.nodeName } }
+var k8sEnvVars = []struct {
+	key  attribute.Key
+	envs []string
+}{
+	{semconv.K8SPodNameKey, []string{"K8S_POD_NAME", "POD_NAME"}},
+	{semconv.K8SPodUIDKey, []string{"K8S_POD_UID", "POD_UID"}},
+	{semconv.K8SNamespaceNameKey, []string{"K8S_NAMESPACE_NAME", "POD_NAMESPACE"}},
+	{semconv.K8SNodeNameKey, []string{"K8S_NODE_NAME", "NODE_NAME"}},
+	{semconv.K8SContainerNameKey, []string{"K8S_CONTAINER_NAME"}},
+}
+
+// NewResource 构建描述当前服务实例的 OTel Resource，可同时用于 TracerProvider、MeterProvider 和 LoggerProvider
+// 属性来源按优先级从低到高合并 (后者覆盖前者):
+//  1. SDK 默认属性 (telemetry.sdk.*)
+//  2. 主机名、进程运行时信息、容器 ID (cgroup)、K8s pod/namespace/node (Downward API 环境变量)
+//  3. cfg.Attributes 中的静态属性与部署环境 (cfg.Environment 或 DEPLOY_ENV)
+//  4. OTEL_RESOURCE_ATTRIBUTES / OTEL_SERVICE_NAME 环境变量
+//  5. serviceName / serviceVersion 参数
+//
+// 个别检测器失败 (例如不在容器中运行) 不会导致整体失败，已检测到的属性仍然会被返回。
+func NewResource(ctx context.Context, serviceName, serviceVersion string, cfg config.ResourceConfig) (*resource.Resource, error) {
+	opts := []resource.Option{
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithTelemetrySDK(),
+	}
+	if !cfg.DisableHost {
+		opts = append(opts, resource.WithHost())
+	}
+	if !cfg.DisableProcess {
+		opts = append(opts,
+			resource.WithProcessPID(),
+			resource.WithProcessExecutableName(),
+			resource.WithProcessRuntimeName(),
+			resource.WithProcessRuntimeVersion(),
+			resource.WithProcessRuntimeDescription(),
+		)
+	}
+	if !cfg.DisableContainer {
+		opts = append(opts, resource.WithContainerID())
+	}
+	if !cfg.DisableK8s {
+		opts = append(opts, resource.WithDetectors(k8sDetector{}))
+	}
+
+	staticAttrs := make([]attribute.KeyValue, 0, len(cfg.Attributes)+1)
+	for k, v := range cfg.Attributes {
+		staticAttrs = append(staticAttrs, attribute.String(k, v))
+	}
+	environment := cfg.Environment
+	if environment == "" {
+		environment = os.Getenv(deploymentEnvVar)
+	}
+	if environment != "" {
+		staticAttrs = append(staticAttrs, semconv.DeploymentEnvironmentKey.String(environment))
+	}
+	opts = append(opts,
+		resource.WithAttributes(staticAttrs...),
+		resource.WithFromEnv(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceVersionKey.String(serviceVersion),
+		),
+	)
+
+	res, err := resource.New(ctx, opts...)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("创建 resource 失败: %w", err)
+	}
+	return res, nil
+}
+
+// k8sDetector 从 Downward API 注入的环境变量中读取 K8s 属性
+type k8sDetector struct{}
+
+var _ resource.Detector = k8sDetector{}
+
+// Detect 实现 resource.Detector 接口，没有任何 K8s 环境变量时返回空 Resource
+func (k8sDetector) Detect(context.Context) (*resource.Resource, error) {
+	attrs := make([]attribute.KeyValue, 0, len(k8sEnvVars))
+	for _, item := range k8sEnvVars {
+		for _, env := range item.envs {
+			if v := os.Getenv(env); v != "" {
+				attrs = append(attrs, item.key.String(v))
+				break
+			}
+		}
+	}
+	if len(attrs) == 0 {
+		return resource.Empty(), nil
+	}
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...), nil
+}
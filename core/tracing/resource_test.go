@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TestNewResource 检查各属性来源的合并及优先级: 检测 < 配置 < OTEL_RESOURCE_ATTRIBUTES < 参数
+func TestNewResource(t *testing.T) {
+	cases := []struct {
+		name string
+		env  map[string]string
+		cfg  config.ResourceConfig
+		want map[string]string // 值为空表示该属性不应存在
+	}{
+		{
+			name: "默认只有服务名和版本",
+			want: map[string]string{"service.name": "svc", "service.version": "v1", "deployment.environment": "", "k8s.pod.name": ""},
+		},
+		{
+			name: "部署环境来自 DEPLOY_ENV",
+			env:  map[string]string{"DEPLOY_ENV": "staging"},
+			want: map[string]string{"deployment.environment": "staging"},
+		},
+		{
+			name: "配置的部署环境优先于 DEPLOY_ENV",
+			env:  map[string]string{"DEPLOY_ENV": "staging"},
+			cfg:  config.ResourceConfig{Environment: "prod"},
+			want: map[string]string{"deployment.environment": "prod"},
+		},
+		{
+			name: "Downward API 环境变量按顺序取第一个非空值",
+			env:  map[string]string{"K8S_POD_NAME": "pod-a", "POD_NAME": "pod-b", "POD_NAMESPACE": "ns", "NODE_NAME": "node-1"},
+			want: map[string]string{"k8s.pod.name": "pod-a", "k8s.namespace.name": "ns", "k8s.node.name": "node-1"},
+		},
+		{
+			name: "关闭 K8s 检测",
+			env:  map[string]string{"K8S_POD_NAME": "pod-a"},
+			cfg:  config.ResourceConfig{DisableK8s: true},
+			want: map[string]string{"k8s.pod.name": ""},
+		},
+		{
+			name: "配置的静态属性覆盖检测结果",
+			env:  map[string]string{"K8S_POD_NAME": "pod-a"},
+			cfg:  config.ResourceConfig{Attributes: map[string]string{"k8s.pod.name": "explicit", "team": "core"}},
+			want: map[string]string{"k8s.pod.name": "explicit", "team": "core"},
+		},
+		{
+			name: "OTEL_RESOURCE_ATTRIBUTES 覆盖配置，服务名参数覆盖 OTEL_SERVICE_NAME",
+			env:  map[string]string{"OTEL_RESOURCE_ATTRIBUTES": "team=platform,region=cn", "OTEL_SERVICE_NAME": "env-svc", "DEPLOY_ENV": "staging"},
+			cfg:  config.ResourceConfig{Attributes: map[string]string{"team": "core"}},
+			want: map[string]string{"team": "platform", "region": "cn", "service.name": "svc", "deployment.environment": "staging"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range []string{"DEPLOY_ENV", "OTEL_RESOURCE_ATTRIBUTES", "OTEL_SERVICE_NAME", "K8S_POD_NAME", "POD_NAME", "K8S_POD_UID", "POD_UID", "K8S_NAMESPACE_NAME", "POD_NAMESPACE", "K8S_NODE_NAME", "NODE_NAME", "K8S_CONTAINER_NAME"} {
+				t.Setenv(k, tc.env[k])
+			}
+			tc.cfg.DisableHost, tc.cfg.DisableProcess, tc.cfg.DisableContainer = true, true, true
+
+			res, err := NewResource(context.Background(), "svc", "v1", tc.cfg)
+			if err != nil {
+				t.Fatalf("NewResource: %v", err)
+			}
+			set := res.Set()
+			for k, want := range tc.want {
+				v, ok := set.Value(attribute.Key(k))
+				switch {
+				case want == "" && ok:
+					t.Errorf("%s 不应存在，实际 %q", k, v.Emit())
+				case want != "" && v.Emit() != want:
+					t.Errorf("%s 应为 %q，实际 %q", k, want, v.Emit())
+				}
+			}
+		})
+	}
+}
+
+// TestNewResourceDetectors 开启的检测器会写入主机和进程属性
+func TestNewResourceDetectors(t *testing.T) {
+	res, err := NewResource(context.Background(), "svc", "v1", config.ResourceConfig{DisableContainer: true})
+	if err != nil {
+		t.Fatalf("NewResource: %v", err)
+	}
+	for _, k := range []attribute.Key{"host.name", "process.pid", "process.runtime.name"} {
+		if _, ok := res.Set().Value(k); !ok {
+			t.Errorf("应检测到 %s", k)
+		}
+	}
+}
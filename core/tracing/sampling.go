@@ -0,0 +1,231 @@
+package tracing
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 当前已注册到 TracerProvider 的可热更新采样组件，由 InitTracerProvider 设置，UpdateSampling 读取
+var (
+	activeMu      sync.Mutex
+	activeSampler *dynamicSampler
+	activeTail    *tailSamplingProcessor
+)
+
+// UpdateSampling 使用新的 TracerConfig 热更新采样配置 (采样器类型、比例、路由规则、尾部采样阈值)
+// 典型用法是作为 core.LoadConfig 的热加载回调:
+//
+//	core.LoadConfig(path, &cfg, func() { _ = tracing.UpdateSampling(cfg.Tracing) })
+//
+// 如果追踪尚未初始化 (或已禁用)，该函数什么也不做；配置无效时返回错误并保留旧配置。
+func UpdateSampling(cfg config.TracerConfig) error {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+
+	if activeSampler == nil {
+		return nil
+	}
+	rs, err := newRuleSet(cfg)
+	if err != nil {
+		return err
+	}
+	activeSampler.rules.Store(rs)
+	if activeTail != nil {
+		activeTail.configure(cfg)
+	}
+	return nil
+}
+
+// dynamicSampler 是一个可热更新的采样器
+// - 对有本地父 Span 的 Span，沿用父 Span 的采样结果，保证同一进程内的 Trace 完整
+// - 对根 Span 或远程父 Span (即进入本服务的请求)，先按路由规则匹配，未命中时使用默认采样器
+type dynamicSampler struct {
+	rules atomic.Pointer[ruleSet]
+}
+
+var _ sdktrace.Sampler = (*dynamicSampler)(nil)
+
+// newDynamicSampler 根据配置创建可热更新的采样器
+func newDynamicSampler(cfg config.TracerConfig) (*dynamicSampler, error) {
+	rs, err := newRuleSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &dynamicSampler{}
+	s.rules.Store(rs)
+	return s, nil
+}
+
+// ShouldSample 实现 sdktrace.Sampler 接口
+func (s *dynamicSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	if psc.IsValid() && !psc.IsRemote() {
+		// 本地父 Span 的决策对整个进程内的 Trace 生效，否则根 Span 被丢弃时子 Span 仍会作为孤立 Span 导出
+		decision := sdktrace.Drop
+		if psc.IsSampled() {
+			decision = sdktrace.RecordAndSample
+		}
+		return sdktrace.SamplingResult{Decision: decision, Tracestate: psc.TraceState()}
+	}
+
+	rs := s.rules.Load()
+	if rule := rs.match(p); rule != nil {
+		return rule.sampler.ShouldSample(p)
+	}
+	return rs.fallback.ShouldSample(p)
+}
+
+// Description 实现 sdktrace.Sampler 接口
+func (s *dynamicSampler) Description() string {
+	return fmt.Sprintf("GoCommonDynamicSampler{%s}", s.rules.Load().fallback.Description())
+}
+
+// ruleSet 是某一时刻生效的采样配置快照，热更新时整体替换
+type ruleSet struct {
+	rules    []compiledRule
+	fallback sdktrace.Sampler
+}
+
+// compiledRule 是预处理后的 config.SamplingRule
+type compiledRule struct {
+	path    string
+	prefix  bool
+	methods map[string]struct{}
+	sampler sdktrace.Sampler
+}
+
+// newRuleSet 根据配置构建采样规则快照
+func newRuleSet(cfg config.TracerConfig) (*ruleSet, error) {
+	rs := &ruleSet{}
+	switch cfg.SamplerType {
+	case "traceid_ratio", "parent_based_traceid_ratio", "rule_based":
+		if cfg.SamplerParam < 0 || cfg.SamplerParam > 1 {
+			return nil, fmt.Errorf("sampler 类型 %s 的 sampler_param 必须在 [0, 1] 之间: %v", cfg.SamplerType, cfg.SamplerParam)
+		}
+	}
+	switch cfg.SamplerType {
+	case "always_on":
+		rs.fallback = sdktrace.AlwaysSample()
+	case "always_off":
+		rs.fallback = sdktrace.NeverSample()
+	case "traceid_ratio":
+		rs.fallback = sdktrace.TraceIDRatioBased(cfg.SamplerParam) // cfg.SamplerParam 是采样率，如 0.1
+	case "parent_based_traceid_ratio":
+		// 如果父 Span 被采样，则子 Span 也采样；否则根据比例采样（推荐用于微服务）
+		rs.fallback = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplerParam))
+	case "rule_based":
+		// 规则未命中的请求按 parent_based_traceid_ratio 处理
+		rs.fallback = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplerParam))
+	case "tail_based":
+		// 尾部采样要求头部尽量全部采样，真正的取舍由 tailSamplingProcessor 决定
+		rs.fallback = sdktrace.AlwaysSample()
+	default:
+		return nil, fmt.Errorf("不支持的 sampler 类型: %s", cfg.SamplerType)
+	}
+
+	if cfg.SamplerType != "rule_based" && cfg.SamplerType != "tail_based" {
+		return rs, nil
+	}
+	for i, r := range cfg.SamplingRules {
+		if r.Path == "" {
+			return nil, fmt.Errorf("第 %d 条采样规则缺少 path", i)
+		}
+		if r.Ratio < 0 || r.Ratio > 1 {
+			return nil, fmt.Errorf("第 %d 条采样规则 (%s) 的 ratio 必须在 [0, 1] 之间: %v", i, r.Path, r.Ratio)
+		}
+		cr := compiledRule{path: r.Path}
+		if strings.HasSuffix(r.Path, "*") {
+			cr.path = strings.TrimSuffix(r.Path, "*")
+			cr.prefix = true
+		}
+		if len(r.Methods) > 0 {
+			cr.methods = make(map[string]struct{}, len(r.Methods))
+			for _, m := range r.Methods {
+				cr.methods[strings.ToUpper(m)] = struct{}{}
+			}
+		}
+		switch r.Ratio {
+		case 1:
+			cr.sampler = sdktrace.AlwaysSample()
+		case 0:
+			cr.sampler = sdktrace.NeverSample()
+		default:
+			cr.sampler = sdktrace.TraceIDRatioBased(r.Ratio)
+		}
+		rs.rules = append(rs.rules, cr)
+	}
+	return rs, nil
+}
+
+// match 返回第一条命中的规则，没有命中时返回 nil
+func (rs *ruleSet) match(p sdktrace.SamplingParameters) *compiledRule {
+	if len(rs.rules) == 0 {
+		return nil
+	}
+	method, path := routeFromParameters(p)
+	if path == "" {
+		return nil
+	}
+	for i := range rs.rules {
+		r := &rs.rules[i]
+		if r.prefix {
+			if !strings.HasPrefix(path, r.path) {
+				continue
+			}
+		} else if path != r.path {
+			continue
+		}
+		if r.methods != nil {
+			if _, ok := r.methods[method]; !ok {
+				continue
+			}
+		}
+		return r
+	}
+	return nil
+}
+
+// routeFromParameters 从 Span 的启动属性中提取 HTTP 方法和路由
+// 优先使用 http.route (路由模板)，其次是 url.path / http.target；都没有时尝试解析形如 "GET /path" 的 Span 名称
+func routeFromParameters(p sdktrace.SamplingParameters) (method, path string) {
+	var route, urlPath string
+	for _, kv := range p.Attributes {
+		switch kv.Key {
+		case "http.request.method", "http.method":
+			method = strings.ToUpper(kv.Value.AsString())
+		case "http.route":
+			route = kv.Value.AsString()
+		case "url.path":
+			urlPath = kv.Value.AsString()
+		case "http.target":
+			if urlPath == "" {
+				urlPath, _, _ = strings.Cut(kv.Value.AsString(), "?")
+			}
+		}
+	}
+
+	path = route
+	if path == "" {
+		path = urlPath
+	}
+	if path == "" || method == "" {
+		if m, rest, ok := strings.Cut(p.Name, " "); ok && strings.HasPrefix(rest, "/") {
+			if method == "" {
+				method = strings.ToUpper(m)
+			}
+			if path == "" {
+				path = rest
+			}
+		} else if path == "" && strings.HasPrefix(p.Name, "/") {
+			path = p.Name
+		}
+	}
+	return method, path
+}
@@ -0,0 +1,236 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// samplingRules 依次为: 从不采样健康检查、总是采样 /api 下的 POST、从不采样 /api 下的其他请求
+var samplingRules = []config.SamplingRule{
+	{Path: "/healthz", Ratio: 0},
+	{Path: "/api/*", Methods: []string{"post"}, Ratio: 1},
+	{Path: "/api/*", Ratio: 0},
+}
+
+func newTestSampler(t *testing.T, cfg config.TracerConfig) *dynamicSampler {
+	t.Helper()
+	s, err := newDynamicSampler(cfg)
+	if err != nil {
+		t.Fatalf("newDynamicSampler: %v", err)
+	}
+	return s
+}
+
+// sampled 对一个根 Span 调用采样器，返回是否采样
+func sampled(s sdktrace.Sampler, name string, attrs ...attribute.KeyValue) bool {
+	res := s.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{1},
+		Name:          name,
+		Attributes:    attrs,
+	})
+	return res.Decision == sdktrace.RecordAndSample
+}
+
+// TestDynamicSamplerRuleOrder 规则按顺序匹配，第一条命中的规则生效，未命中时使用默认采样器
+func TestDynamicSamplerRuleOrder(t *testing.T) {
+	s := newTestSampler(t, config.TracerConfig{SamplerType: "rule_based", SamplerParam: 1, SamplingRules: samplingRules})
+
+	cases := []struct {
+		name  string
+		attrs []attribute.KeyValue
+		want  bool
+	}{
+		{"GET /healthz", nil, false},
+		{"POST /api/posts", nil, true},
+		{"GET /api/posts", nil, false},
+		{"GET /other", nil, true},
+		{"handler", []attribute.KeyValue{attribute.String("http.request.method", "POST"), attribute.String("http.route", "/api/posts/:id")}, true},
+		{"handler", []attribute.KeyValue{attribute.String("http.method", "GET"), attribute.String("http.target", "/healthz?probe=1")}, false},
+	}
+	for _, tc := range cases {
+		if got := sampled(s, tc.name, tc.attrs...); got != tc.want {
+			t.Errorf("%s %v: 采样结果应为 %v，实际 %v", tc.name, tc.attrs, tc.want, got)
+		}
+	}
+}
+
+// TestDynamicSamplerParentBased 本地父 Span 的决策优先于规则，远程父 Span 仍按规则匹配
+func TestDynamicSamplerParentBased(t *testing.T) {
+	s := newTestSampler(t, config.TracerConfig{SamplerType: "rule_based", SamplerParam: 1, SamplingRules: samplingRules})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(s))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	tracer := tp.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "GET /other")
+	_, child := tracer.Start(ctx, "GET /healthz")
+	if !child.SpanContext().IsSampled() {
+		t.Error("采样的本地父 Span 下，子 Span 应沿用采样决策")
+	}
+	child.End()
+	root.End()
+
+	ctx, root = tracer.Start(context.Background(), "GET /healthz")
+	_, child = tracer.Start(ctx, "POST /api/posts")
+	if root.SpanContext().IsSampled() || child.SpanContext().IsSampled() {
+		t.Error("未采样的本地父 Span 下，子 Span 应同样被丢弃")
+	}
+	child.End()
+	root.End()
+
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{2},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	_, span := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "GET /healthz")
+	if span.SpanContext().IsSampled() {
+		t.Error("远程父 Span 的请求应按路由规则采样")
+	}
+	span.End()
+}
+
+// TestUpdateSampling 热更新替换规则，配置无效时保留旧配置，未初始化时不做任何事
+func TestUpdateSampling(t *testing.T) {
+	activeMu.Lock()
+	prevSampler, prevTail := activeSampler, activeTail
+	activeSampler, activeTail = nil, nil
+	activeMu.Unlock()
+	t.Cleanup(func() {
+		activeMu.Lock()
+		activeSampler, activeTail = prevSampler, prevTail
+		activeMu.Unlock()
+	})
+
+	if err := UpdateSampling(config.TracerConfig{SamplerType: "unknown"}); err != nil {
+		t.Fatalf("未初始化时 UpdateSampling 应什么也不做，实际 %v", err)
+	}
+
+	s := newTestSampler(t, config.TracerConfig{SamplerType: "always_on"})
+	tail := newTailSamplingProcessor(sdktrace.NewSimpleSpanProcessor(tracetest.NewInMemoryExporter()), config.TracerConfig{SamplerType: "always_on"})
+	activeMu.Lock()
+	activeSampler, activeTail = s, tail
+	activeMu.Unlock()
+
+	if err := UpdateSampling(config.TracerConfig{SamplerType: "tail_based", SamplingRules: samplingRules}); err != nil {
+		t.Fatalf("UpdateSampling: %v", err)
+	}
+	if sampled(s, "GET /healthz") || !sampled(s, "GET /other") {
+		t.Error("热更新后应按新的规则采样")
+	}
+	if !tail.enabled.Load() {
+		t.Error("热更新应同时开启尾部采样")
+	}
+
+	if err := UpdateSampling(config.TracerConfig{SamplerType: "traceid_ratio", SamplerParam: 2}); err == nil {
+		t.Error("无效的配置应返回错误")
+	}
+	if sampled(s, "GET /healthz") || !sampled(s, "GET /other") {
+		t.Error("配置无效时应保留旧的规则")
+	}
+}
+
+// newTailTracer 返回经过尾部采样处理器的 Tracer 和接收导出 Span 的内存 Exporter
+func newTailTracer(t *testing.T, tail config.TailSamplingConfig) (trace.Tracer, *tailSamplingProcessor, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	p := newTailSamplingProcessor(sdktrace.NewSimpleSpanProcessor(exporter), config.TracerConfig{SamplerType: "tail_based", TailSampling: tail})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(p))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp.Tracer("test"), p, exporter
+}
+
+// TestTailSamplingKeepOnError 包含错误 Span 的 Trace 在本地 Span 全部结束后整体导出
+func TestTailSamplingKeepOnError(t *testing.T) {
+	tracer, _, exporter := newTailTracer(t, config.TailSamplingConfig{})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Fatalf("Trace 未完成前不应导出，实际 %d 个 Span", n)
+	}
+	root.End()
+	if n := len(exporter.GetSpans()); n != 2 {
+		t.Errorf("包含错误的 Trace 应整体导出，实际 %d 个 Span", n)
+	}
+}
+
+// TestTailSamplingKeepOnLatency 任一 Span 耗时超过阈值时保留整个 Trace，否则丢弃
+func TestTailSamplingKeepOnLatency(t *testing.T) {
+	tracer, _, exporter := newTailTracer(t, config.TailSamplingConfig{LatencyThresholdMs: 100})
+	start := time.Now()
+
+	ctx, root := tracer.Start(context.Background(), "slow", trace.WithTimestamp(start))
+	_, child := tracer.Start(ctx, "child", trace.WithTimestamp(start))
+	child.End(trace.WithTimestamp(start.Add(10 * time.Millisecond)))
+	root.End(trace.WithTimestamp(start.Add(150 * time.Millisecond)))
+	if n := len(exporter.GetSpans()); n != 2 {
+		t.Fatalf("超过耗时阈值的 Trace 应整体导出，实际 %d 个 Span", n)
+	}
+	exporter.Reset()
+
+	ctx, root = tracer.Start(context.Background(), "fast", trace.WithTimestamp(start))
+	_, child = tracer.Start(ctx, "child", trace.WithTimestamp(start))
+	child.End(trace.WithTimestamp(start.Add(10 * time.Millisecond)))
+	root.End(trace.WithTimestamp(start.Add(50 * time.Millisecond)))
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("没有错误且未超过阈值的 Trace 应被丢弃，实际导出 %d 个 Span", n)
+	}
+}
+
+// TestTailSamplingEviction 缓存达到上限时驱逐最早的 Trace，驱逐后结束的 Span 沿用驱逐时的决策
+func TestTailSamplingEviction(t *testing.T) {
+	tracer, p, exporter := newTailTracer(t, config.TailSamplingConfig{MaxPendingTraces: 2})
+
+	// Trace A: 子 Span 已出错 (决定保留)，根 Span 尚未结束
+	ctxA, rootA := tracer.Start(context.Background(), "a")
+	_, childA := tracer.Start(ctxA, "a-child")
+	childA.SetStatus(codes.Error, "boom")
+	childA.End()
+	// Trace B: 尚无保留理由
+	_, rootB := tracer.Start(context.Background(), "b")
+	// Trace C 和 D 依次驱逐 A 和 B
+	_, rootC := tracer.Start(context.Background(), "c")
+	_, rootD := tracer.Start(context.Background(), "d")
+
+	p.mu.Lock()
+	pending := len(p.pending)
+	p.mu.Unlock()
+	if pending != 2 {
+		t.Errorf("缓存中的 Trace 数量不应超过上限 2，实际 %d", pending)
+	}
+	if names := spanNames(exporter.GetSpans()); len(names) != 1 || names[0] != "a-child" {
+		t.Errorf("驱逐时应导出已决定保留的 Trace A，实际 %v", names)
+	}
+
+	rootA.End()
+	rootB.End()
+	if names := spanNames(exporter.GetSpans()); len(names) != 2 || names[1] != "a" {
+		t.Errorf("被驱逐的 Trace 应沿用驱逐时的决策 (A 保留，B 丢弃)，实际 %v", names)
+	}
+	rootC.End()
+	rootD.End()
+	if n := len(exporter.GetSpans()); n != 2 {
+		t.Errorf("没有保留理由的 Trace 应被丢弃，实际导出 %d 个 Span", n)
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name
+	}
+	return names
+}
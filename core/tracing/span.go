@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName 是业务代码通过 Start 创建 Span 时统一使用的 Tracer 名称
+const TracerName = "github.com/Xushengqwer/go-common"
+
+// 业务领域 ID 的属性键
+const (
+	PostIDKey  = attribute.Key("app.post.id")
+	UserIDKey  = attribute.Key("app.user.id")
+	EventIDKey = attribute.Key("app.event.id")
+)
+
+// sentinelEvent 描述一个 commonerrors 哨兵错误在 Span 上的表现
+// - name: 记录到 Span 上的事件名
+// - isError: 是否把 Span 状态标记为 Error；"数据没找到"、"用户未登录" 属于正常的业务结果，不算错误
+type sentinelEvent struct {
+	err     error
+	name    string
+	isError bool
+}
+
+// sentinelEvents 定义 commonerrors 哨兵错误到 Span 事件的映射
+var sentinelEvents = []sentinelEvent{
+	{commonerrors.ErrRepoNotFound, "repo.not_found", false},
+	{commonerrors.ErrUserNotLoggedIn, "user.not_logged_in", false},
+	{commonerrors.ErrVersionConflict, "version.conflict", false},
+	{commonerrors.ErrServiceBusy, "service.busy", true},
+	{commonerrors.ErrSystemError, "system.error", true},
+	{commonerrors.ErrThirdPartyServiceError, "third_party.error", true},
+}
+
+// Start 使用统一的 Tracer 名称创建一个 Span，并返回结束 Span 的闭包
+// 推荐用法 (err 需要是具名返回值，这样 end 才能看到最终的错误):
+//
+//	func (s *PostService) Get(ctx context.Context, id uint64) (post *Post, err error) {
+//		ctx, span, end := tracing.Start(ctx, "PostService.Get", trace.WithAttributes(tracing.PostID(id)))
+//		defer end(&err)
+//		...
+//	}
+//
+// end(&err) 会在 err 非 nil 时记录错误并设置 Span 状态；commonerrors 中的哨兵错误会额外记录为 Span 事件。
+// 如果函数发生 panic，end 会把 panic 记录到 Span 上并重新抛出。
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span, func(*error)) {
+	ctx, span := otel.Tracer(TracerName).Start(ctx, name, opts...)
+	end := func(errp *error) {
+		if r := recover(); r != nil {
+			span.RecordError(fmt.Errorf("panic: %v", r), trace.WithStackTrace(true))
+			span.SetStatus(codes.Error, "panic")
+			span.End()
+			panic(r)
+		}
+		if errp != nil {
+			RecordError(span, *errp)
+		}
+		span.End()
+	}
+	return ctx, span, end
+}
+
+// RecordError 把错误记录到 Span 上并设置状态，err 为 nil 时什么也不做
+// commonerrors 中的哨兵错误会记录为对应的 Span 事件；其中属于正常业务结果的 (例如 ErrRepoNotFound) 不会把 Span 标记为 Error
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	for _, se := range sentinelEvents {
+		if errors.Is(err, se.err) {
+			span.AddEvent(se.name, trace.WithAttributes(attribute.String("error.message", err.Error())))
+			if !se.isError {
+				return
+			}
+			break
+		}
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// PostID 返回帖子 ID 属性
+// 以十进制字符串记录: uint64 的 ID (例如 core/idgen 生成的 Snowflake ID) 可能超出 int64 范围，转换为 Int64 属性会变成负数
+func PostID(id uint64) attribute.KeyValue {
+	return PostIDKey.String(strconv.FormatUint(id, 10))
+}
+
+// UserID 返回用户 ID 属性
+func UserID(id string) attribute.KeyValue {
+	return UserIDKey.String(id)
+}
+
+// EventID 返回事件 ID 属性 (例如 Kafka 事件的 EventID)
+func EventID(id string) attribute.KeyValue {
+	return EventIDKey.String(id)
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newGlobalRecorder 把记录 Span 的 TracerProvider 设置为全局 Provider (Start 使用全局 Tracer)
+func newGlobalRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	restoreGlobals(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+// TestStartRecordsErrors 哨兵错误记录为事件，只有不属于正常业务结果的错误把 Span 标记为 Error
+func TestStartRecordsErrors(t *testing.T) {
+	cases := []struct {
+		err        error
+		event      string
+		wantStatus codes.Code
+	}{
+		{nil, "", codes.Unset},
+		{fmt.Errorf("查询帖子: %w", commonerrors.ErrRepoNotFound), "repo.not_found", codes.Unset},
+		{commonerrors.ErrUserNotLoggedIn, "user.not_logged_in", codes.Unset},
+		{commonerrors.ErrVersionConflict, "version.conflict", codes.Unset},
+		{commonerrors.ErrServiceBusy, "service.busy", codes.Error},
+		{fmt.Errorf("调用支付: %w", commonerrors.ErrThirdPartyServiceError), "third_party.error", codes.Error},
+		{errors.New("boom"), "", codes.Error},
+	}
+	for _, tc := range cases {
+		recorder := newGlobalRecorder(t)
+		func() (err error) {
+			_, _, end := Start(context.Background(), "op")
+			defer end(&err)
+			return tc.err
+		}()
+
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("%v: 应结束 1 个 Span，实际 %d", tc.err, len(spans))
+		}
+		s := spans[0]
+		if s.Status().Code != tc.wantStatus {
+			t.Errorf("%v: Span 状态应为 %v，实际 %v", tc.err, tc.wantStatus, s.Status().Code)
+		}
+		var names []string
+		for _, e := range s.Events() {
+			names = append(names, e.Name)
+		}
+		if tc.event != "" && (len(names) == 0 || names[0] != tc.event) {
+			t.Errorf("%v: 应记录事件 %s，实际 %v", tc.err, tc.event, names)
+		}
+		hasException := false
+		for _, n := range names {
+			hasException = hasException || n == "exception"
+		}
+		if hasException != (tc.wantStatus == codes.Error) {
+			t.Errorf("%v: 只有标记为 Error 的 Span 才应记录 exception 事件，实际 %v", tc.err, names)
+		}
+	}
+}
+
+// TestStartPanic end 把 panic 记录到 Span 上并重新抛出
+func TestStartPanic(t *testing.T) {
+	recorder := newGlobalRecorder(t)
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("panic 应被重新抛出，实际 %v", r)
+		}
+		spans := recorder.Ended()
+		if len(spans) != 1 {
+			t.Fatalf("panic 时 Span 也应结束，实际 %d 个", len(spans))
+		}
+		if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "panic" {
+			t.Errorf("Span 状态应为 Error(panic)，实际 %v", spans[0].Status())
+		}
+		if len(spans[0].Events()) == 0 || spans[0].Events()[0].Name != "exception" {
+			t.Errorf("应记录 exception 事件，实际 %v", spans[0].Events())
+		}
+	}()
+
+	func() (err error) {
+		_, _, end := Start(context.Background(), "op")
+		defer end(&err)
+		panic("boom")
+	}()
+}
+
+// TestPostID 超出 int64 范围的 ID 不会变成负数
+func TestPostID(t *testing.T) {
+	if v := PostID(math.MaxUint64).Value.AsString(); v != "18446744073709551615" {
+		t.Errorf("PostID 应以十进制字符串记录，实际 %q", v)
+	}
+	if v := PostID(42).Value.AsString(); v != "42" {
+		t.Errorf("PostID(42) 应为 \"42\"，实际 %q", v)
+	}
+}
@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultMaxPendingTraces 是未配置 MaxPendingTraces 时内存中最多缓存的未完成 Trace 数量
+const defaultMaxPendingTraces = 10000
+
+// tailSamplingProcessor 是一个包装下游 SpanProcessor (通常是 BatchSpanProcessor) 的尾部采样处理器
+// 它按 TraceID 缓存本进程内的 Span，当某个 Trace 的本地 Span 全部结束后再整体决策:
+// - 任一 Span 的状态为 Error，或任一 Span 耗时超过 LatencyThresholdMs，则把整个本地 Trace 交给下游导出
+// - 否则整体丢弃
+// 未启用 (SamplerType 不是 "tail_based") 时直接透传，几乎没有额外开销。
+type tailSamplingProcessor struct {
+	next sdktrace.SpanProcessor
+
+	enabled          atomic.Bool
+	latencyThreshold atomic.Int64 // 纳秒，<= 0 表示只按错误保留
+	maxPending       atomic.Int64
+
+	mu      sync.Mutex
+	pending map[trace.TraceID]*pendingTrace
+	order   *list.List // 按开始时间排列的未完成 Trace (元素值为 trace.TraceID)，缓存已满时 O(1) 驱逐最早的一个
+
+	// 最近被驱逐的 Trace 及其决策，用于处理驱逐之后才开始或结束的 Span，最多保留 maxPending 个
+	evicted      map[trace.TraceID]bool
+	evictedOrder []trace.TraceID
+}
+
+// pendingTrace 是一个尚未做出决策的本地 Trace
+type pendingTrace struct {
+	open  int // 尚未结束的本地 Span 数量
+	keep  bool
+	elem  *list.Element // 在 tailSamplingProcessor.order 中的位置
+	spans []sdktrace.ReadOnlySpan
+}
+
+var _ sdktrace.SpanProcessor = (*tailSamplingProcessor)(nil)
+
+// newTailSamplingProcessor 创建尾部采样处理器，next 是决策保留后真正接收 Span 的处理器
+func newTailSamplingProcessor(next sdktrace.SpanProcessor, cfg config.TracerConfig) *tailSamplingProcessor {
+	p := &tailSamplingProcessor{
+		next:    next,
+		pending: make(map[trace.TraceID]*pendingTrace),
+		order:   list.New(),
+		evicted: make(map[trace.TraceID]bool),
+	}
+	p.configure(cfg)
+	return p
+}
+
+// configure 应用 (或热更新) 尾部采样配置
+// 关闭尾部采样时，已在缓存中的 Trace 会继续按原规则完成决策，新的 Trace 直接透传
+func (p *tailSamplingProcessor) configure(cfg config.TracerConfig) {
+	p.latencyThreshold.Store(int64(time.Duration(cfg.TailSampling.LatencyThresholdMs) * time.Millisecond))
+	maxPending := cfg.TailSampling.MaxPendingTraces
+	if maxPending <= 0 {
+		maxPending = defaultMaxPendingTraces
+	}
+	p.maxPending.Store(int64(maxPending))
+	p.enabled.Store(cfg.SamplerType == "tail_based")
+}
+
+// OnStart 实现 sdktrace.SpanProcessor 接口，登记本地 Trace 中新开启的 Span
+func (p *tailSamplingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+	if !p.enabled.Load() || !s.SpanContext().IsSampled() {
+		return
+	}
+
+	var evicted *pendingTrace
+	p.mu.Lock()
+	tid := s.SpanContext().TraceID()
+	if _, ok := p.evicted[tid]; ok {
+		// 所属 Trace 已被驱逐并做出决策，OnEnd 按该决策处理，不再重新登记为新的 Trace
+		p.mu.Unlock()
+		return
+	}
+	pt, ok := p.pending[tid]
+	if !ok {
+		if int64(len(p.pending)) >= p.maxPending.Load() {
+			evicted = p.evictOldestLocked()
+		}
+		pt = &pendingTrace{elem: p.order.PushBack(tid)}
+		p.pending[tid] = pt
+	}
+	pt.open++
+	p.mu.Unlock()
+
+	if evicted != nil {
+		p.flush(evicted)
+	}
+}
+
+// OnEnd 实现 sdktrace.SpanProcessor 接口，缓存结束的 Span，并在本地 Trace 完成时做出决策
+func (p *tailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	tid := s.SpanContext().TraceID()
+	pt, ok := p.pending[tid]
+	if !ok {
+		keep, evicted := p.evicted[tid]
+		p.mu.Unlock()
+		// 已被驱逐的 Trace 沿用驱逐时的决策: 丢弃的 Trace 不能再导出零散的 Span，否则后端会收到不完整的 Trace
+		// 未登记的 Span (未启用尾部采样时开启) 直接透传
+		if !evicted || keep {
+			p.next.OnEnd(s)
+		}
+		return
+	}
+
+	pt.spans = append(pt.spans, s)
+	pt.open--
+	if !pt.keep && p.shouldKeep(s) {
+		pt.keep = true
+	}
+	if pt.open > 0 {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, tid)
+	p.order.Remove(pt.elem)
+	p.mu.Unlock()
+
+	p.flush(pt)
+}
+
+// Shutdown 实现 sdktrace.SpanProcessor 接口，对缓存中剩余的 Trace 按当前信息做出决策后关闭下游
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	remaining := p.pending
+	p.pending = make(map[trace.TraceID]*pendingTrace)
+	p.order.Init()
+	p.evicted = make(map[trace.TraceID]bool)
+	p.evictedOrder = nil
+	p.mu.Unlock()
+
+	for _, pt := range remaining {
+		p.flush(pt)
+	}
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush 实现 sdktrace.SpanProcessor 接口
+// 未完成的 Trace 还不能做出决策，因此只刷新下游已接收的 Span
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// shouldKeep 判断单个 Span 是否足以让整个 Trace 被保留
+func (p *tailSamplingProcessor) shouldKeep(s sdktrace.ReadOnlySpan) bool {
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	threshold := time.Duration(p.latencyThreshold.Load())
+	return threshold > 0 && s.EndTime().Sub(s.StartTime()) >= threshold
+}
+
+// flush 把已做出保留决策的 Trace 交给下游，否则丢弃
+func (p *tailSamplingProcessor) flush(pt *pendingTrace) {
+	if !pt.keep {
+		return
+	}
+	for _, s := range pt.spans {
+		p.next.OnEnd(s)
+	}
+}
+
+// evictOldestLocked 在缓存已满时移除最早开始的 Trace 并记住它的决策，调用方必须持有 p.mu
+// 被驱逐 Trace 中后续结束的 Span 按该决策处理: 已决定保留的继续导出，否则丢弃
+func (p *tailSamplingProcessor) evictOldestLocked() *pendingTrace {
+	front := p.order.Front()
+	if front == nil {
+		return nil
+	}
+	tid := p.order.Remove(front).(trace.TraceID)
+	oldest := p.pending[tid]
+	delete(p.pending, tid)
+
+	for int64(len(p.evictedOrder)) >= p.maxPending.Load() && len(p.evictedOrder) > 0 {
+		delete(p.evicted, p.evictedOrder[0])
+		p.evictedOrder = p.evictedOrder[1:]
+	}
+	p.evictedOrder = append(p.evictedOrder, tid)
+	p.evicted[tid] = oldest.keep
+	return oldest
+}
@@ -9,24 +9,22 @@ import (
 
 	"go.opentelemetry.io/otel"
 
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
-	// 可能需要导入 jaeger exporter 等
 	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0" // 使用最新的语义约定
+	"go.uber.org/zap"
 )
 
 // InitTracerProvider 初始化并注册全局的 OpenTelemetry TracerProvider
 // -- serviceName: 当前服务的名称 (重要!)
 // -- serviceVersion: 当前服务的版本 (可选)
 // -- cfg: 从服务配置中加载的 TracerConfig
+// -- opts: 可选配置，例如 WithLogger(logger) 输出结构化日志，WithLenientSampler() 恢复宽松的采样配置校验
 // 返回值: shutdown 函数用于优雅关闭，以及可能出现的错误
-func InitTracerProvider(serviceName, serviceVersion string, cfg config.TracerConfig) (func(context.Context) error, error) {
+// 采样器类型未知或 SamplerParam 不在 [0, 1] 之间时返回错误 (除非使用 WithLenientSampler)。
+func InitTracerProvider(serviceName, serviceVersion string, cfg config.TracerConfig, opts ...Option) (func(context.Context) error, error) {
+	o := newOptions(opts)
 	if !cfg.Enabled {
-		fmt.Println("分布式追踪已禁用.")
+		o.info("分布式追踪已禁用")
 		// 返回一个无操作的 shutdown 函数和 nil 错误
 		return func(context.Context) error { return nil }, nil
 	}
@@ -34,88 +32,68 @@ func InitTracerProvider(serviceName, serviceVersion string, cfg config.TracerCon
 	// 创建一个背景上下文，用于初始化过程
 	ctx := context.Background()
 
-	// 1. 创建 Exporter (根据配置选择)
-	var exporter sdktrace.SpanExporter
-	var err error
-	switch cfg.ExporterType {
-	case "otlp_grpc":
-		// 注意: 生产环境通常需要配置 TLS, headers (API Key) 等
-		opts := []otlptracegrpc.Option{
-			otlptracegrpc.WithEndpoint(cfg.ExporterEndpoint),
-			//  todo 注意：生产环境通常需要加密传输 (TLS)！这里 WithInsecure 是为了方便测试不加密
-			otlptracegrpc.WithInsecure(),
-			otlptracegrpc.WithTimeout(5 * time.Second),
-		}
-		exporter, err = otlptracegrpc.New(ctx, opts...)
-	case "otlp_http":
-		opts := []otlptracehttp.Option{
-			otlptracehttp.WithEndpoint(cfg.ExporterEndpoint),
-			otlptracehttp.WithInsecure(), // 生产环境应移除或配置 TLS
-			// otlptracehttp.WithURLPath("/v1/traces"), // 有些 OTLP 接收端需要特定的 URL 路径
-		}
-		exporter, err = otlptracehttp.New(ctx, opts...)
-	case "stdout":
-		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
-	// case "jaeger":
-	// ... Jaeger exporter setup ...
-	default:
-		err = fmt.Errorf("不支持的 exporter 类型: %s", cfg.ExporterType)
-	}
+	// 1. 创建 Sampler (根据配置选择)
+	// 使用可热更新的 dynamicSampler，之后可通过 UpdateSampling 调整采样类型、比例和路由规则
+	// 放在创建 Exporter 之前，配置无效时尽早失败
+	sampler, err := newDynamicSampler(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("创建 %s exporter 失败: %w", cfg.ExporterType, err)
+		if !o.lenientSampler {
+			return nil, fmt.Errorf("创建 sampler 失败: %w", err)
+		}
+		o.warn("采样配置无效，将使用 AlwaysSample", zap.Error(err), zap.String("sampler_type", cfg.SamplerType))
+		sampler, _ = newDynamicSampler(config.TracerConfig{SamplerType: "always_on"})
 	}
 
-	// 2. 创建 Sampler (根据配置选择)
-	var sampler sdktrace.Sampler
-	switch cfg.SamplerType {
-	case "always_on":
-		sampler = sdktrace.AlwaysSample()
-	case "always_off":
-		sampler = sdktrace.NeverSample()
-	case "traceid_ratio":
-		sampler = sdktrace.TraceIDRatioBased(cfg.SamplerParam) // cfg.SamplerParam 是采样率，如 0.1
-	case "parent_based_traceid_ratio":
-		// 如果父 Span 被采样，则子 Span 也采样；否则根据比例采样（推荐用于微服务）
-		sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplerParam))
-	default:
-		fmt.Printf("不支持的 sampler 类型: %s，将使用 AlwaysSample\n", cfg.SamplerType)
-		sampler = sdktrace.AlwaysSample()
+	// 2. 创建 Resource (服务名、版本、部署环境、主机、容器、K8s 等通用属性)
+	// 放在创建 Exporter 之前，失败时不会遗留已建立连接的 Exporter
+	res, err := NewResource(ctx, serviceName, serviceVersion, cfg.Resource)
+	if err != nil {
+		return nil, err
 	}
 
-	// 3. 创建 Resource (包含服务名、版本等通用属性)
-	res, err := resource.Merge(
-		resource.Default(),
-		resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(serviceName),
-			semconv.ServiceVersionKey.String(serviceVersion),
-			// 可以添加环境 (prod/dev), k8s pod name 等属性
-			// attribute.String("environment", environment),
-		),
-	)
+	// 3. 创建 Exporter (根据配置选择，"multi" 模式会同时扇出到多个 Exporter)
+	exporter, err := newExporter(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("创建 resource 失败: %w", err)
+		return nil, fmt.Errorf("创建 %s exporter 失败: %w", cfg.ExporterType, err)
 	}
 
 	// 4. 创建 TracerProvider
 	// 使用 BatchSpanProcessor 提高性能，异步批量导出 Span
+	// 外层包装尾部采样处理器：SamplerType 为 "tail_based" 时按本地 Trace 整体决策，否则直接透传
 	bsp := sdktrace.NewBatchSpanProcessor(exporter)
+	tail := newTailSamplingProcessor(bsp, cfg)
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithSampler(sampler),
 		sdktrace.WithResource(res),
-		sdktrace.WithSpanProcessor(bsp),
+		sdktrace.WithSpanProcessor(tail),
 	)
 
+	// 记录当前生效的采样组件，供 UpdateSampling 热更新
+	activeMu.Lock()
+	activeSampler, activeTail = sampler, tail
+	activeMu.Unlock()
+
 	// 5. 注册为全局 Provider 和 Propagator
 	otel.SetTracerProvider(tp)
+	if o.logger != nil {
+		// 把 OTel SDK 的内部错误 (例如 Exporter 导出失败) 输出到 zap，而不是默认的标准库 log
+		logger := o.logger
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+			logger.Error("OpenTelemetry 内部错误", zap.Error(err))
+		}))
+	}
 	// 使用 W3C Trace Context (标准) 和 Baggage 进行上下文传播
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{}, // 必须
 		propagation.Baggage{},      // 可选，用于传递业务自定义数据
 	))
 
-	fmt.Printf("分布式追踪初始化完成: ServiceName=%s, Exporter=%s, Sampler=%s(%.2f)\n",
-		serviceName, cfg.ExporterType, cfg.SamplerType, cfg.SamplerParam)
+	o.info("分布式追踪初始化完成",
+		zap.String("service_name", serviceName),
+		zap.String("exporter_type", cfg.ExporterType),
+		zap.String("sampler_type", cfg.SamplerType),
+		zap.Float64("sampler_param", cfg.SamplerParam),
+	)
 
 	// 返回 shutdown 函数，用于程序退出时优雅地 flush 数据
 	shutdown := func(ctx context.Context) error {
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// restoreGlobals 在测试结束后还原 InitTracerProvider 修改的全局状态
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	tp, handler, propagator := otel.GetTracerProvider(), otel.GetErrorHandler(), otel.GetTextMapPropagator()
+	activeMu.Lock()
+	sampler, tail := activeSampler, activeTail
+	activeMu.Unlock()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(tp)
+		otel.SetErrorHandler(handler)
+		otel.SetTextMapPropagator(propagator)
+		activeMu.Lock()
+		activeSampler, activeTail = sampler, tail
+		activeMu.Unlock()
+	})
+}
+
+func newObservedLogger() (*core.ZapLogger, *observer.ObservedLogs) {
+	zc, logs := observer.New(zapcore.DebugLevel)
+	return core.WrapZapLogger(zap.New(zc)), logs
+}
+
+// TestInitTracerProviderUnknownSampler 未知的采样器类型默认返回错误，只有 WithLenientSampler 时回退到 AlwaysSample
+func TestInitTracerProviderUnknownSampler(t *testing.T) {
+	restoreGlobals(t)
+	cfg := config.TracerConfig{Enabled: true, ExporterType: "stdout", SamplerType: "sometimes"}
+
+	if _, err := InitTracerProvider("svc", "v1", cfg); err == nil {
+		t.Fatal("未知的采样器类型应返回错误")
+	}
+
+	logger, logs := newObservedLogger()
+	shutdown, err := InitTracerProvider("svc", "v1", cfg, WithLenientSampler(), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("WithLenientSampler 时不应返回错误: %v", err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+
+	if logs.FilterMessage("采样配置无效，将使用 AlwaysSample").FilterLevelExact(zapcore.WarnLevel).Len() != 1 {
+		t.Error("回退时应记录一条 Warn 日志")
+	}
+	activeMu.Lock()
+	desc := activeSampler.Description()
+	activeMu.Unlock()
+	if !strings.Contains(desc, "AlwaysOnSampler") {
+		t.Errorf("应回退到 AlwaysSample，实际 %s", desc)
+	}
+}
+
+// TestInitTracerProviderErrorHandler OTel SDK 的内部错误通过 WithLogger 注入的 logger 输出
+func TestInitTracerProviderErrorHandler(t *testing.T) {
+	restoreGlobals(t)
+	logger, logs := newObservedLogger()
+	shutdown, err := InitTracerProvider("svc", "v1", config.TracerConfig{Enabled: true, ExporterType: "stdout", SamplerType: "always_on"}, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("InitTracerProvider: %v", err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+
+	otel.Handle(errors.New("export failed"))
+
+	entries := logs.FilterMessage("OpenTelemetry 内部错误").AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("应记录 1 条 OTel 内部错误日志，实际 %d", len(entries))
+	}
+	if entries[0].Level != zapcore.ErrorLevel || entries[0].ContextMap()["error"] != "export failed" {
+		t.Errorf("日志内容错误: %v %v", entries[0].Level, entries[0].ContextMap())
+	}
+}
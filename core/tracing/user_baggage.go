@@ -0,0 +1,222 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/core/identity"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// 用户身份在 OTel Baggage 中使用的键名
+const (
+	BaggageUserID     = "user.id"
+	BaggageUserRole   = "user.role"
+	BaggageUserStatus = "user.status"
+	BaggagePlatform   = "user.platform"
+)
+
+// 身份签名 (core/identity) 在 OTel Baggage 中使用的键名
+const (
+	BaggageIdentityTimestamp = "user.identity.ts"
+	BaggageIdentityKeyID     = "user.identity.kid"
+	BaggageIdentitySignature = "user.identity.sig"
+)
+
+// userBaggageFields 定义 context 键与 Baggage 键的对应关系
+var userBaggageFields = []struct {
+	ctxKey     any
+	baggageKey string
+}{
+	{constants.UserIDKey, BaggageUserID},
+	{constants.RoleKey, BaggageUserRole},
+	{constants.StatusKey, BaggageUserStatus},
+	{constants.PlatformKey, BaggagePlatform},
+}
+
+// BaggageAllowList 是允许通过 Baggage 传播的键名白名单
+// 出站时不在白名单中的 Baggage 成员会被移除，入站时只有白名单中的键会被还原到 context
+type BaggageAllowList map[string]struct{}
+
+// DefaultUserBaggageAllowList 默认只允许传播用户身份相关的四个键及其签名
+var DefaultUserBaggageAllowList = NewBaggageAllowList(
+	BaggageUserID, BaggageUserRole, BaggageUserStatus, BaggagePlatform,
+	BaggageIdentityTimestamp, BaggageIdentityKeyID, BaggageIdentitySignature,
+)
+
+// NewBaggageAllowList 根据键名列表创建白名单
+func NewBaggageAllowList(keys ...string) BaggageAllowList {
+	allow := make(BaggageAllowList, len(keys))
+	for _, k := range keys {
+		allow[k] = struct{}{}
+	}
+	return allow
+}
+
+// Allows 判断键名是否在白名单中
+func (a BaggageAllowList) Allows(key string) bool {
+	_, ok := a[key]
+	return ok
+}
+
+// UserBaggageOption 配置 InjectUserBaggage / ExtractUserBaggage
+type UserBaggageOption func(*userBaggageOptions)
+
+type userBaggageOptions struct {
+	signer   *identity.Signer
+	verifier *identity.Verifier
+	trusted  bool
+}
+
+// WithBaggageSigner 出站时用网关同一套密钥 (core/identity) 对写入 Baggage 的用户身份签名
+func WithBaggageSigner(s *identity.Signer) UserBaggageOption {
+	return func(o *userBaggageOptions) {
+		o.signer = s
+	}
+}
+
+// WithBaggageVerifier 入站时只还原签名有效且在重放窗口内的用户身份
+// Kafka 等异步消费可能在签名很久之后才处理消息，需要为 Verifier 配置足够大的 ReplayWindow。
+func WithBaggageVerifier(v *identity.Verifier) UserBaggageOption {
+	return func(o *userBaggageOptions) {
+		o.verifier = v
+	}
+}
+
+// WithTrustedBaggage 声明 Baggage 来自受信任的内部传输 (例如只有内部服务能写入的 Kafka topic)，不校验签名直接还原身份
+// 不能用于直接接收外部请求的入口，否则任何客户端都可以通过 baggage 请求头冒充其他用户。
+func WithTrustedBaggage() UserBaggageOption {
+	return func(o *userBaggageOptions) {
+		o.trusted = true
+	}
+}
+
+// InjectUserBaggage 在发起下游调用 (HTTP / Kafka) 之前，把 context 中的用户身份写入 OTel Baggage
+// - 从 constants.UserIDKey / RoleKey / StatusKey / PlatformKey 读取 string 类型的值，空值会被跳过
+// - 现有 Baggage 中不在 allow 白名单里的成员会被移除，保证只有批准的键离开本服务
+// - allow 为 nil 时使用 DefaultUserBaggageAllowList
+// - 使用 WithBaggageSigner 时同时写入签名 (user.identity.ts / kid / sig)，下游用 WithBaggageVerifier 校验
+//
+// 返回的 context 交给已注册的全局 Propagator (InitTracerProvider 注册了 W3C Baggage) 注入请求头或消息头:
+//
+//	ctx, err := tracing.InjectUserBaggage(ctx, nil, tracing.WithBaggageSigner(signer))
+//	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+func InjectUserBaggage(ctx context.Context, allow BaggageAllowList, opts ...UserBaggageOption) (context.Context, error) {
+	var o userBaggageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if allow == nil {
+		allow = DefaultUserBaggageAllowList
+	}
+
+	bag := baggage.FromContext(ctx)
+	for _, m := range bag.Members() {
+		if !allow.Allows(m.Key()) {
+			bag = bag.DeleteMember(m.Key())
+		}
+	}
+
+	values := make(map[string]string, len(userBaggageFields)+3)
+	for _, f := range userBaggageFields {
+		if !allow.Allows(f.baggageKey) {
+			continue
+		}
+		if val, _ := ctx.Value(f.ctxKey).(string); val != "" {
+			values[f.baggageKey] = val
+		}
+	}
+	if o.signer != nil && values[BaggageUserID] != "" {
+		sig := o.signer.SignIdentity(baggageIdentity(values))
+		values[BaggageIdentityTimestamp] = sig.Timestamp
+		values[BaggageIdentityKeyID] = sig.KeyID
+		values[BaggageIdentitySignature] = sig.Value
+	}
+
+	for _, key := range []string{
+		BaggageUserID, BaggageUserRole, BaggageUserStatus, BaggagePlatform,
+		BaggageIdentityTimestamp, BaggageIdentityKeyID, BaggageIdentitySignature,
+	} {
+		val, ok := values[key]
+		if !ok {
+			// 上游传来的旧签名与本次写入的身份不再对应
+			bag = bag.DeleteMember(key)
+			continue
+		}
+		member, err := baggage.NewMemberRaw(key, val)
+		if err != nil {
+			return ctx, fmt.Errorf("创建 baggage 成员 %s 失败: %w", key, err)
+		}
+		if bag, err = bag.SetMember(member); err != nil {
+			return ctx, fmt.Errorf("写入 baggage 成员 %s 失败: %w", key, err)
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
+// ExtractUserBaggage 在接收到上游调用后，把 Baggage 中的用户身份还原到 context
+// 调用前需要先用全局 Propagator 从请求头或消息头中提取 Baggage (otelgin 等埋点会自动完成):
+//
+//	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+//	ctx = tracing.ExtractUserBaggage(ctx, nil, tracing.WithBaggageVerifier(verifier))
+//
+// Baggage 来自请求头，任何客户端都可以伪造，因此必须使用 WithBaggageVerifier (校验签名) 或 WithTrustedBaggage
+// (受信任的内部传输) 之一，否则不会还原任何身份。
+// - 还原的身份经过 identity.Parse 校验后通过 identity.ContextWithUser 写入，middleware.CurrentUser(ctx) 可以直接读取
+// - 只有 allow 白名单中的键会被读取 (nil 时使用 DefaultUserBaggageAllowList)
+// - 签名无效、身份无效或 context 中已有用户身份时原样返回 ctx
+func ExtractUserBaggage(ctx context.Context, allow BaggageAllowList, opts ...UserBaggageOption) context.Context {
+	var o userBaggageOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.verifier == nil && !o.trusted {
+		return ctx
+	}
+	if allow == nil {
+		allow = DefaultUserBaggageAllowList
+	}
+
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return ctx
+	}
+	if _, ok := identity.UserFromContext(ctx); ok {
+		return ctx
+	}
+
+	values := make(map[string]string, len(userBaggageFields)+3)
+	for _, m := range bag.Members() {
+		if allow.Allows(m.Key()) {
+			values[m.Key()] = m.Value()
+		}
+	}
+	id := baggageIdentity(values)
+	if o.verifier != nil {
+		err := o.verifier.VerifyIdentity(id, identity.Signature{
+			KeyID:     values[BaggageIdentityKeyID],
+			Timestamp: values[BaggageIdentityTimestamp],
+			Value:     values[BaggageIdentitySignature],
+		})
+		if err != nil {
+			return ctx
+		}
+	}
+	user, err := identity.Parse(id)
+	if err != nil {
+		return ctx
+	}
+	return identity.ContextWithUser(ctx, user)
+}
+
+// baggageIdentity 把 Baggage 中的身份成员转换为 identity.Identity
+func baggageIdentity(values map[string]string) identity.Identity {
+	return identity.Identity{
+		UserID:   values[BaggageUserID],
+		Role:     values[BaggageUserRole],
+		Status:   values[BaggageUserStatus],
+		Platform: values[BaggagePlatform],
+	}
+}
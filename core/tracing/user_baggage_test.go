@@ -0,0 +1,189 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/core/identity"
+	"github.com/Xushengqwer/go-common/models/enums"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+var testSigningConfig = config.IdentitySigningConfig{
+	KeyID: "k1",
+	Keys:  map[string]string{"k1": strings.Repeat("a", 32)},
+}
+
+// withBaggage 返回带有指定 Baggage 成员的 context
+func withBaggage(t *testing.T, ctx context.Context, members map[string]string) context.Context {
+	t.Helper()
+	bag := baggage.FromContext(ctx)
+	for k, v := range members {
+		m, err := baggage.NewMemberRaw(k, v)
+		if err != nil {
+			t.Fatalf("NewMemberRaw(%s): %v", k, err)
+		}
+		if bag, err = bag.SetMember(m); err != nil {
+			t.Fatalf("SetMember(%s): %v", k, err)
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// userContext 返回写入了用户身份的 context
+func userContext() context.Context {
+	return identity.ContextWithUser(context.Background(), &identity.UserContext{
+		UserID: "u-1", Role: enums.RoleUser, Status: enums.StatusActive, Platform: enums.PlatformWeb,
+	})
+}
+
+// transmit 通过 W3C Baggage 请求头把 ctx 中的 Baggage 传给一个新的 context，模拟一次跨服务调用
+func transmit(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	propagation.Baggage{}.Inject(ctx, carrier)
+	return propagation.Baggage{}.Extract(context.Background(), carrier)
+}
+
+func newTestSigner(t *testing.T) (*identity.Signer, *identity.Verifier) {
+	t.Helper()
+	signer, err := identity.NewSigner(testSigningConfig)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	verifier, err := identity.NewVerifier(testSigningConfig)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return signer, verifier
+}
+
+// TestInjectUserBaggageAllowList 出站时移除不在白名单中的成员，只写入白名单中的身份字段
+func TestInjectUserBaggageAllowList(t *testing.T) {
+	ctx := withBaggage(t, userContext(), map[string]string{
+		"session.token":          "secret",
+		BaggageUserID:            "forged",
+		BaggageIdentitySignature: "stale",
+	})
+
+	out, err := InjectUserBaggage(ctx, nil)
+	if err != nil {
+		t.Fatalf("InjectUserBaggage: %v", err)
+	}
+	bag := baggage.FromContext(out)
+	if bag.Member("session.token").Key() != "" {
+		t.Error("不在白名单中的成员应被移除")
+	}
+	if bag.Member(BaggageIdentitySignature).Key() != "" {
+		t.Error("未签名时上游的旧签名应被移除")
+	}
+	want := map[string]string{BaggageUserID: "u-1", BaggageUserRole: "user", BaggageUserStatus: "active", BaggagePlatform: "web"}
+	for k, v := range want {
+		if got := bag.Member(k).Value(); got != v {
+			t.Errorf("%s 应为 %q，实际 %q", k, v, got)
+		}
+	}
+
+	out, err = InjectUserBaggage(ctx, NewBaggageAllowList(BaggageUserID))
+	if err != nil {
+		t.Fatalf("InjectUserBaggage: %v", err)
+	}
+	if bag := baggage.FromContext(out); bag.Len() != 1 || bag.Member(BaggageUserID).Value() != "u-1" {
+		t.Errorf("自定义白名单只应保留 user.id，实际 %v", bag.Members())
+	}
+}
+
+// TestUserBaggageSignedRoundTrip 签名的身份经过传输后可以被校验并还原
+func TestUserBaggageSignedRoundTrip(t *testing.T) {
+	signer, verifier := newTestSigner(t)
+
+	out, err := InjectUserBaggage(userContext(), nil, WithBaggageSigner(signer))
+	if err != nil {
+		t.Fatalf("InjectUserBaggage: %v", err)
+	}
+	ctx := ExtractUserBaggage(transmit(out), nil, WithBaggageVerifier(verifier))
+
+	user, ok := identity.UserFromContext(ctx)
+	if !ok {
+		t.Fatal("签名有效的身份应被还原")
+	}
+	if user.UserID != "u-1" || user.Role != enums.RoleUser || user.Status != enums.StatusActive || user.Platform != enums.PlatformWeb {
+		t.Errorf("还原的身份错误: %+v", user)
+	}
+	if ctx.Value(constants.UserIDKey) != "u-1" {
+		t.Error("还原的身份应同时写入 constants.UserIDKey")
+	}
+}
+
+// TestExtractUserBaggageRejectsUntrusted 未配置信任方式、未签名或被篡改的 Baggage 不会还原身份
+func TestExtractUserBaggageRejectsUntrusted(t *testing.T) {
+	signer, verifier := newTestSigner(t)
+	unsigned := map[string]string{BaggageUserID: "u-1", BaggageUserRole: "admin", BaggageUserStatus: "active"}
+
+	signedCtx, err := InjectUserBaggage(userContext(), nil, WithBaggageSigner(signer))
+	if err != nil {
+		t.Fatalf("InjectUserBaggage: %v", err)
+	}
+	signed := transmit(signedCtx)
+
+	otherSigner, err := identity.NewSigner(config.IdentitySigningConfig{KeyID: "k2", Keys: map[string]string{"k2": strings.Repeat("b", 32)}})
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	foreignCtx, err := InjectUserBaggage(userContext(), nil, WithBaggageSigner(otherSigner))
+	if err != nil {
+		t.Fatalf("InjectUserBaggage: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		opts []UserBaggageOption
+	}{
+		{"没有信任选项", withBaggage(t, context.Background(), unsigned), nil},
+		{"没有信任选项 (即使带有有效签名)", signed, nil},
+		{"未签名", withBaggage(t, context.Background(), unsigned), []UserBaggageOption{WithBaggageVerifier(verifier)}},
+		{"篡改角色", withBaggage(t, signed, map[string]string{BaggageUserRole: "admin"}), []UserBaggageOption{WithBaggageVerifier(verifier)}},
+		{"篡改用户 ID", withBaggage(t, signed, map[string]string{BaggageUserID: "u-2"}), []UserBaggageOption{WithBaggageVerifier(verifier)}},
+		{"未知密钥", transmit(foreignCtx), []UserBaggageOption{WithBaggageVerifier(verifier)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := ExtractUserBaggage(tc.ctx, nil, tc.opts...)
+			if user, ok := identity.UserFromContext(ctx); ok {
+				t.Errorf("不应还原身份，实际 %+v", user)
+			}
+		})
+	}
+}
+
+// TestExtractUserBaggageTrusted 受信任的传输不校验签名，但身份仍需有效，且只读取白名单中的键
+func TestExtractUserBaggageTrusted(t *testing.T) {
+	members := map[string]string{BaggageUserID: "u-1", BaggageUserRole: "guest", BaggageUserStatus: "active"}
+
+	ctx := ExtractUserBaggage(withBaggage(t, context.Background(), members), nil, WithTrustedBaggage())
+	if user, ok := identity.UserFromContext(ctx); !ok || user.UserID != "u-1" || user.Role != enums.RoleGuest {
+		t.Errorf("受信任的 Baggage 应直接还原身份，实际 %+v", user)
+	}
+
+	invalid := withBaggage(t, context.Background(), map[string]string{BaggageUserID: "u-1", BaggageUserRole: "0", BaggageUserStatus: "active"})
+	if user, ok := identity.UserFromContext(ExtractUserBaggage(invalid, nil, WithTrustedBaggage())); ok {
+		t.Errorf("无效的身份不应被还原，实际 %+v", user)
+	}
+
+	allow := NewBaggageAllowList(BaggageUserID, BaggageUserStatus)
+	ctx = ExtractUserBaggage(withBaggage(t, context.Background(), members), allow, WithTrustedBaggage())
+	if user, ok := identity.UserFromContext(ctx); ok {
+		t.Errorf("白名单之外的 user.role 不应被读取 (身份缺少角色)，实际 %+v", user)
+	}
+
+	existing := userContext()
+	ctx = ExtractUserBaggage(withBaggage(t, existing, members), nil, WithTrustedBaggage())
+	if user, _ := identity.UserFromContext(ctx); user.Role != enums.RoleUser {
+		t.Errorf("context 中已有的身份不应被 Baggage 覆盖，实际 %+v", user)
+	}
+}
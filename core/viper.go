@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
@@ -21,16 +22,18 @@ import (
 //  1. 设置环境变量的读取规则，这是最高优先级的配置源。
 //  2. 确定配置文件的路径，优先级为：环境变量 APP_CONFIG_PATH > 命令行 -config 标志 > 硬编码默认值。
 //  3. 如果配置文件存在，则加载它作为配置的“基础”或“默认值”。
-//  4. 将所有配置源（文件 + 环境变量）合并，并最终解析（Unmarshal）到传入的结构体指针中。
+//  4. 将所有配置源（文件 + 环境变量）合并，并最终解析（Unmarshal）到传入的结构体指针中，
+//     然后对实现了 Validate() error 的配置 (包括嵌套的字段，例如 config.RequestLogConfig) 逐一校验。
 //  5. （可选）启动对配置文件的热加载监控。
 //
 // 参数:
 //   - configPathFromFlag: 从命令行 -config 标志接收到的配置文件路径。
 //   - cfgPtr: 需要被填充配置的目标结构体的指针 (e.g., &config.AppConfig)。
+//   - onReload: （可选）热重载成功后依次调用的回调，用于把新配置应用到已初始化的组件 (e.g., tracing.UpdateSampling)。
 //
 // 返回:
-//   - error: 如果在加载或解析过程中发生不可恢复的错误，则返回错误。
-func LoadConfig(configPathFromFlag string, cfgPtr interface{}) error {
+//   - error: 如果在加载、解析或校验过程中发生不可恢复的错误，则返回错误。
+func LoadConfig(configPathFromFlag string, cfgPtr interface{}, onReload ...func()) error {
 	// 初始化一个新的 Viper 实例，避免使用全局单例，以保证配置的隔离性。
 	v := viper.New()
 
@@ -86,6 +89,9 @@ func LoadConfig(configPathFromFlag string, cfgPtr interface{}) error {
 	if err := v.Unmarshal(cfgPtr); err != nil {
 		return fmt.Errorf("无法将最终配置解析到结构体: %w", err)
 	}
+	if err := validateConfig(reflect.ValueOf(cfgPtr)); err != nil {
+		return fmt.Errorf("配置校验失败: %w", err)
+	}
 
 	log.Println("配置加载和解析成功。")
 
@@ -100,11 +106,56 @@ func LoadConfig(configPathFromFlag string, cfgPtr interface{}) error {
 			// 当文件变化时，再次执行 Unmarshal，将新的配置加载到原始的结构体指针中。
 			if err := v.Unmarshal(cfgPtr); err != nil {
 				log.Printf("热重载配置文件失败: %v", err)
+			} else if err := validateConfig(reflect.ValueOf(cfgPtr)); err != nil {
+				// 已初始化的组件不会收到无效配置
+				log.Printf("热重载的配置校验失败，不通知组件: %v", err)
 			} else {
 				log.Printf("配置已通过热重载更新。")
+				for _, fn := range onReload {
+					fn()
+				}
 			}
 		})
 	}
 
 	return nil
 }
+
+// configValidator 由需要在加载阶段校验的配置结构体实现 (e.g., config.RequestLogConfig)
+type configValidator interface {
+	Validate() error
+}
+
+// validateConfig 递归遍历配置结构体，对实现了 configValidator 的值调用 Validate
+func validateConfig(v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	// 可寻址时通过指针断言，同时覆盖值接收者和指针接收者的 Validate
+	target := v
+	if v.CanAddr() {
+		target = v.Addr()
+	}
+	if target.CanInterface() {
+		if validator, ok := target.Interface().(configValidator); ok {
+			if err := validator.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		if err := validateConfig(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+)
+
+type testAppConfig struct {
+	RequestLog config.RequestLogConfig `mapstructure:"request_log"`
+}
+
+// writeConfigFile 在临时目录写入 YAML 配置文件并返回路径
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	t.Setenv("APP_CONFIG_PATH", "")
+	return path
+}
+
+// TestLoadConfigValidates 嵌套配置段的 Validate 在加载时执行，无效的正则返回错误而不是 panic
+func TestLoadConfigValidates(t *testing.T) {
+	path := writeConfigFile(t, "request_log:\n  skipPathRegexps: [\"(\"]\n")
+
+	var cfg testAppConfig
+	err := LoadConfig(path, &cfg)
+	if err == nil || !strings.Contains(err.Error(), "skipPathRegexps") {
+		t.Fatalf("无效的 skipPathRegexps 应返回错误，实际 %v", err)
+	}
+}
+
+// TestLoadConfigValid 有效的配置正常加载
+func TestLoadConfigValid(t *testing.T) {
+	path := writeConfigFile(t, "request_log:\n  skipPathRegexps: [\"\\\\.js$\"]\n")
+
+	var cfg testAppConfig
+	if err := LoadConfig(path, &cfg); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(cfg.RequestLog.SkipPathRegexps) != 1 || cfg.RequestLog.SkipPathRegexps[0] != `\.js$` {
+		t.Errorf("skipPathRegexps 解析错误: %q", cfg.RequestLog.SkipPathRegexps)
+	}
+}
@@ -110,6 +110,12 @@ func NewZapLogger(cfg config.ZapConfig) (*ZapLogger, error) {
 	return &ZapLogger{logger: logger}, nil
 }
 
+// WrapZapLogger 用已有的 *zap.Logger 创建 ZapLogger，例如接入服务自己构建的 Logger 或测试中的 observer
+// 与 NewZapLogger 一样跳过一层调用栈，调用位置指向调用 ZapLogger 方法的代码行
+func WrapZapLogger(logger *zap.Logger) *ZapLogger {
+	return &ZapLogger{logger: logger.WithOptions(zap.AddCallerSkip(1))}
+}
+
 // Debug 记录 Debug 级别的日志
 // - msg: 日志消息
 // - fields: 可选的附加字段，用于提供上下文信息
@@ -15,7 +15,26 @@ import (
 	"gorm.io/gorm/utils"
 )
 
-var _ logger.Interface = (*GormLogger)(nil)
+var (
+	_ logger.Interface  = (*GormLogger)(nil)
+	_ gorm.ParamsFilter = (*GormLogger)(nil)
+)
+
+// GormLogger 输出的 SQL 日志消息，慢查询、错误和普通查询使用不同的消息，方便告警规则直接匹配
+const (
+	gormMsgQueryError = "SQL query failed"
+	gormMsgQuerySlow  = "SQL query slow"
+	gormMsgQuery      = "SQL query executed"
+)
+
+// GormLogger 字段名的默认值 (OTel 风格)
+const (
+	defaultGormFieldStatement    = "db.statement"
+	defaultGormFieldRowsAffected = "db.rows_affected"
+	defaultGormFieldDuration     = "duration_ms"
+	defaultGormFieldCaller       = "code.caller"
+	defaultGormFieldArgs         = "db.statement.args"
+)
 
 // GormLogger 内部状态
 type GormLogger struct {
@@ -23,28 +42,51 @@ type GormLogger struct {
 	logLevel                  logger.LogLevel
 	SlowThreshold             time.Duration
 	SkipCallerLookup          bool
-	ignoreRecordNotFoundError bool // 将配置存储在 logger 实例中
+	ignoreRecordNotFoundError bool                     // 将配置存储在 logger 实例中
+	fieldNames                config.GormLogFieldNames // 结构化日志字段名 (已填充默认值)
+	parameterizedQueries      bool                     // 只记录带占位符的 SQL
+	logQueryArgs              bool                     // 占位符模式下单独记录脱敏后的参数
+	redactor                  *sqlRedactor             // 按列脱敏参数、截断过长语句
+	params                    *paramsChannel           // ParamsFilter 把结果交回 Trace 的旁路通道 (LogMode 返回的副本共用)
+	slowQueries               *SlowQueryAggregator     // 慢查询聚合器，未开启聚合时为 nil
+	logEachSlowQuery          bool                     // 开启聚合后是否仍逐条输出慢查询
 }
 
 // NewGormLogger (修改后) - 直接接收共享的 config.GormLogConfig
 // zapLogger: 共享库提供的 ZapLogger 实例
 // cfg: 从服务配置文件加载的共享 GormLogConfig 实例
+// 开启 SlowQueryAggregation 时，第一次记录慢查询会启动后台汇总协程，服务退出前调用 Close 结束它并输出最后一次汇总
 func NewGormLogger(zapLogger *ZapLogger, cfg config.GormLogConfig) *GormLogger {
 	// --- 在这里进行配置转换 ---
-	gormLogLevel := logger.Info // 默认级别
-	switch cfg.Level {
-	case "warn":
-		gormLogLevel = logger.Warn
-	case "error":
-		gormLogLevel = logger.Error
-	case "silent":
-		gormLogLevel = logger.Silent
-	}
+	gormLogLevel := ParseGormLogLevel(cfg.Level)
 
 	slowThreshold := time.Duration(cfg.SlowThresholdMs) * time.Millisecond
 	if slowThreshold <= 0 { // 如果配置为 0 或负数，也给个默认值
 		slowThreshold = 200 * time.Millisecond // 默认 200ms
 	}
+
+	// 未配置的字段名使用默认的 OTel 风格键名
+	fieldNames := cfg.FieldNames
+	if fieldNames.Statement == "" {
+		fieldNames.Statement = defaultGormFieldStatement
+	}
+	if fieldNames.RowsAffected == "" {
+		fieldNames.RowsAffected = defaultGormFieldRowsAffected
+	}
+	if fieldNames.Duration == "" {
+		fieldNames.Duration = defaultGormFieldDuration
+	}
+	if fieldNames.Caller == "" {
+		fieldNames.Caller = defaultGormFieldCaller
+	}
+	if fieldNames.Args == "" {
+		fieldNames.Args = defaultGormFieldArgs
+	}
+	// 开启慢查询聚合时创建聚合器 (第一次记录慢查询时才启动后台汇总协程)
+	var slowQueries *SlowQueryAggregator
+	if cfg.SlowQueryAggregation {
+		slowQueries = NewSlowQueryAggregator(zapLogger, time.Duration(cfg.SlowQueryReportIntervalSec)*time.Second)
+	}
 	// --- 转换结束 ---
 
 	return &GormLogger{
@@ -53,6 +95,68 @@ func NewGormLogger(zapLogger *ZapLogger, cfg config.GormLogConfig) *GormLogger {
 		SlowThreshold:             slowThreshold, // 使用转换后的阈值
 		SkipCallerLookup:          cfg.SkipCallerLookup,
 		ignoreRecordNotFoundError: cfg.IgnoreRecordNotFoundError, // 存储配置值
+		fieldNames:                fieldNames,
+		parameterizedQueries:      cfg.ParameterizedQueries,
+		logQueryArgs:              cfg.LogQueryArgs,
+		redactor:                  newSQLRedactor(cfg),
+		params:                    newParamsChannel(),
+		slowQueries:               slowQueries,
+		logEachSlowQuery:          cfg.LogEachSlowQuery,
+	}
+}
+
+// ParseGormLogLevel 把配置中的级别字符串 ("info"、"warn"、"error"、"silent") 转换为 GORM 日志级别，无法识别时默认 Info
+func ParseGormLogLevel(level string) logger.LogLevel {
+	switch level {
+	case "warn":
+		return logger.Warn
+	case "error":
+		return logger.Error
+	case "silent":
+		return logger.Silent
+	default:
+		return logger.Info
+	}
+}
+
+// gormLogLevelCtxKey 是 context 中保存单个请求 GORM 日志级别覆盖值的键
+type gormLogLevelCtxKey struct{}
+
+// WithGormLogLevel 返回携带 GORM 日志级别覆盖值的 context
+// 使用该 context 执行的查询 (db.WithContext(ctx)) 会按 level 输出日志，不影响其他请求；
+// 通常由 middleware.SQLDebugMiddleware 设置。
+func WithGormLogLevel(ctx context.Context, level logger.LogLevel) context.Context {
+	return context.WithValue(ctx, gormLogLevelCtxKey{}, level)
+}
+
+// GormLogLevelFromContext 返回 context 中的 GORM 日志级别覆盖值，没有设置时第二个返回值为 false
+func GormLogLevelFromContext(ctx context.Context) (logger.LogLevel, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(gormLogLevelCtxKey{}).(logger.LogLevel)
+	return level, ok
+}
+
+// levelFor 返回本次调用生效的日志级别: context 中的覆盖值优先，否则使用实例级别
+func (g *GormLogger) levelFor(ctx context.Context) logger.LogLevel {
+	if level, ok := GormLogLevelFromContext(ctx); ok {
+		return level
+	}
+	return g.logLevel
+}
+
+// SlowQueryAggregator 返回慢查询聚合器，未开启 SlowQueryAggregation 时返回 nil
+// 可用于注册汇总查询接口: router.GET("/debug/slow-queries", middleware.SlowQueryHandler(gormLogger.SlowQueryAggregator()))
+func (g *GormLogger) SlowQueryAggregator() *SlowQueryAggregator {
+	return g.slowQueries
+}
+
+// Close 停止慢查询聚合器的后台协程并输出最后一次汇总，未开启聚合或协程尚未启动时什么也不做
+// LogMode 返回的副本共用同一个聚合器，只需对其中一个调用 Close
+func (g *GormLogger) Close() {
+	if g.slowQueries != nil {
+		g.slowQueries.Stop()
 	}
 }
 
@@ -65,7 +169,7 @@ func (g *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
 
 // Info (保持不变)
 func (g *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
-	if g.logLevel >= logger.Info {
+	if g.levelFor(ctx) >= logger.Info {
 		logFields := g.extractFields(ctx)
 		g.zapLogger.Info(fmt.Sprintf(msg, args...), logFields...)
 	}
@@ -73,7 +177,7 @@ func (g *GormLogger) Info(ctx context.Context, msg string, args ...interface{})
 
 // Warn (保持不变)
 func (g *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
-	if g.logLevel >= logger.Warn {
+	if g.levelFor(ctx) >= logger.Warn {
 		logFields := g.extractFields(ctx)
 		g.zapLogger.Warn(fmt.Sprintf(msg, args...), logFields...)
 	}
@@ -81,38 +185,81 @@ func (g *GormLogger) Warn(ctx context.Context, msg string, args ...interface{})
 
 // Error (保持不变)
 func (g *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
-	if g.logLevel >= logger.Error {
+	if g.levelFor(ctx) >= logger.Error {
 		logFields := g.extractFields(ctx)
 		g.zapLogger.Error(fmt.Sprintf(msg, args...), logFields...)
 	}
 }
 
-// Trace (修改 - 使用实例中的 ignoreRecordNotFoundError)
+// Trace (修改 - 使用实例中的 ignoreRecordNotFoundError，日志级别可被 context 中的覆盖值替换)
 func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
-	if g.logLevel <= logger.Silent {
+	logLevel := g.levelFor(ctx)
+	elapsed := time.Since(begin)
+	slow := elapsed > g.SlowThreshold && g.SlowThreshold != 0
+	// 慢查询聚合与日志级别无关: Silent 级别 (例如 LogMode(logger.Silent) 的会话) 的慢查询同样计入汇总
+	aggregate := slow && g.slowQueries != nil
+	if logLevel <= logger.Silent && !aggregate {
+		return
+	}
+
+	sql, rows, capture := g.params.call(ctx, fc)
+	if !capture.filtered && (g.redactor.enabled() || g.parameterizedQueries) {
+		// db.Scan 等经过 logger.Recorder 的调用不会经过 ParamsFilter，拿到的是已插入原始参数的语句，
+		// 无法再按列脱敏，只能把所有字面量替换为 "?"
+		sql = FingerprintSQL(sql)
+	}
+	args := capture.args
+	fullSQL := sql
+	sql = g.redactor.truncate(sql)
+
+	if aggregate {
+		// 指纹基于截断前的语句计算，避免不同语句被截成相同前缀后合并
+		g.slowQueries.Record(fullSQL, sql, elapsed, rows)
+	}
+	if logLevel <= logger.Silent {
 		return
 	}
 
-	elapsed := time.Since(begin)
-	sql, rows := fc()
 	logFields := g.extractFields(ctx)
-	logFields = append(logFields, zap.Duration("耗时", elapsed))
-	logFields = append(logFields, zap.Int64("行数", rows))
-	logFields = append(logFields, zap.String("SQL", sql))
+	// 耗时以毫秒为单位的数值输出，便于在日志系统中做范围查询和聚合
+	logFields = append(logFields, zap.Float64(g.fieldNames.Duration, durationMs(elapsed)))
+	logFields = append(logFields, zap.Int64(g.fieldNames.RowsAffected, rows))
+	logFields = append(logFields, zap.String(g.fieldNames.Statement, sql))
+	if args != nil {
+		logFields = append(logFields, zap.Strings(g.fieldNames.Args, args))
+	}
 	if !g.SkipCallerLookup {
-		logFields = append(logFields, zap.String("调用者", utils.FileWithLineNum()))
+		logFields = append(logFields, zap.String(g.fieldNames.Caller, utils.FileWithLineNum()))
 	}
 
 	switch {
 	// 使用存储在 GormLogger 实例中的配置来判断是否忽略错误
-	case err != nil && g.logLevel >= logger.Error && !(g.ignoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
-		g.zapLogger.Error("SQL 执行出错", append(logFields, zap.Error(err))...)
-	case elapsed > g.SlowThreshold && g.SlowThreshold != 0 && g.logLevel >= logger.Warn:
-		slowLog := fmt.Sprintf("慢查询 SQL >= %v", g.SlowThreshold)
-		g.zapLogger.Warn(slowLog, logFields...)
-	case g.logLevel >= logger.Info:
-		g.zapLogger.Info("SQL 执行", logFields...)
+	case err != nil && logLevel >= logger.Error && !(g.ignoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)):
+		g.zapLogger.Error(gormMsgQueryError, append(logFields, zap.Error(err))...)
+	// 开启聚合后默认不再逐条输出慢查询，由聚合器定期汇总 (可通过 LogEachSlowQuery 保留逐条日志)
+	case slow && logLevel >= logger.Warn && (g.slowQueries == nil || g.logEachSlowQuery):
+		g.zapLogger.Warn(gormMsgQuerySlow, append(logFields, zap.Int64("slow_threshold_ms", g.SlowThreshold.Milliseconds()))...)
+	case logLevel >= logger.Info:
+		g.zapLogger.Info(gormMsgQuery, logFields...)
+	}
+}
+
+// ParamsFilter 实现 gorm.ParamsFilter 接口，GORM 在 Trace 内部生成日志用的 SQL 之前调用它过滤参数
+// - RedactColumns 中配置的列 (以及无法推断列名的参数) 会被替换为 "***"
+// - ParameterizedQueries 模式下不把参数插入语句，只保留占位符；开启 LogQueryArgs 时脱敏后的参数通过旁路通道交给 Trace 单独记录
+func (g *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	params = g.redactor.redactVars(sql, params)
+	capture := g.params.current(ctx)
+	if capture != nil {
+		capture.filtered = true
+	}
+	if !g.parameterizedQueries {
+		return sql, params
+	}
+	if g.logQueryArgs && capture != nil && len(params) > 0 {
+		capture.args = formatArgs(params)
 	}
+	return sql, nil
 }
 
 // extractFields (保持不变)
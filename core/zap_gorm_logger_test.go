@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm/logger"
+)
+
+func newObservedGormLogger(cfg config.GormLogConfig) (*GormLogger, *observer.ObservedLogs) {
+	zc, logs := observer.New(zapcore.DebugLevel)
+	return NewGormLogger(WrapZapLogger(zap.New(zc)), cfg), logs
+}
+
+// traceQuery 直接调用 Trace 记录一条耗时为 elapsed 的查询
+func traceQuery(g *GormLogger, elapsed time.Duration, err error) {
+	g.Trace(context.Background(), time.Now().Add(-elapsed), func() (string, int64) {
+		return "SELECT * FROM users WHERE id = 1", 1
+	}, err)
+}
+
+// TestGormLoggerFieldNames 默认使用 OTel 风格的键名，配置的 fieldNames 覆盖对应的键，消息按结果区分
+func TestGormLoggerFieldNames(t *testing.T) {
+	cases := []struct {
+		name  string
+		names config.GormLogFieldNames
+		want  []string
+	}{
+		{"默认键名", config.GormLogFieldNames{}, []string{"db.statement", "db.rows_affected", "duration_ms", "code.caller"}},
+		{"自定义键名", config.GormLogFieldNames{Statement: "sql", RowsAffected: "rows", Duration: "elapsed_ms", Caller: "source"}, []string{"sql", "rows", "elapsed_ms", "source"}},
+		{"部分自定义", config.GormLogFieldNames{Statement: "sql"}, []string{"sql", "db.rows_affected", "duration_ms", "code.caller"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g, logs := newObservedGormLogger(config.GormLogConfig{Level: "info", SlowThresholdMs: 100, FieldNames: tc.names})
+
+			traceQuery(g, time.Millisecond, nil)
+			traceQuery(g, 200*time.Millisecond, nil)
+			traceQuery(g, time.Millisecond, errors.New("boom"))
+
+			entries := logs.AllUntimed()
+			wantMsgs := []struct {
+				msg   string
+				level zapcore.Level
+			}{
+				{"SQL query executed", zapcore.InfoLevel},
+				{"SQL query slow", zapcore.WarnLevel},
+				{"SQL query failed", zapcore.ErrorLevel},
+			}
+			if len(entries) != len(wantMsgs) {
+				t.Fatalf("应记录 %d 条日志，实际 %d", len(wantMsgs), len(entries))
+			}
+			for i, e := range entries {
+				if e.Message != wantMsgs[i].msg || e.Level != wantMsgs[i].level {
+					t.Errorf("第 %d 条日志应为 %v %q，实际 %v %q", i, wantMsgs[i].level, wantMsgs[i].msg, e.Level, e.Message)
+				}
+				fields := e.ContextMap()
+				for _, key := range tc.want {
+					if _, ok := fields[key]; !ok {
+						t.Errorf("%q 缺少字段 %q，实际 %v", e.Message, key, fields)
+					}
+				}
+				if fields[tc.want[0]] != "SELECT * FROM users WHERE id = 1" {
+					t.Errorf("语句字段 %q 的值错误: %v", tc.want[0], fields[tc.want[0]])
+				}
+				if _, ok := fields[tc.want[2]].(float64); !ok {
+					t.Errorf("耗时字段 %q 应为毫秒数值，实际 %T", tc.want[2], fields[tc.want[2]])
+				}
+			}
+			if _, ok := entries[1].ContextMap()["slow_threshold_ms"]; !ok {
+				t.Error("慢查询日志应包含 slow_threshold_ms")
+			}
+		})
+	}
+}
+
+// TestGormLoggerArgsFieldName 占位符模式下参数按 fieldNames.Args 记录
+func TestGormLoggerArgsFieldName(t *testing.T) {
+	db, logs := newObservedDB(t, config.GormLogConfig{
+		Level:                "info",
+		ParameterizedQueries: true,
+		LogQueryArgs:         true,
+		SkipCallerLookup:     true,
+		FieldNames:           config.GormLogFieldNames{Args: "params"},
+	})
+	logs.TakeAll()
+
+	var n int64
+	db.Model(&redactedUser{}).Where("name = ?", "alice").Count(&n)
+
+	entries := logs.FilterMessage("SQL query executed").AllUntimed()
+	if len(entries) == 0 {
+		t.Fatal("应记录查询日志")
+	}
+	fields := entries[len(entries)-1].ContextMap()
+	if _, ok := fields["params"]; !ok {
+		t.Errorf("参数应记录在 params 字段中，实际 %v", fields)
+	}
+	if _, ok := fields["code.caller"]; ok {
+		t.Error("SkipCallerLookup 时不应记录调用者字段")
+	}
+}
+
+// TestGormLoggerSilent Silent 级别不输出任何日志
+func TestGormLoggerSilent(t *testing.T) {
+	g, logs := newObservedGormLogger(config.GormLogConfig{Level: "silent"})
+	traceQuery(g, time.Millisecond, errors.New("boom"))
+	if logs.Len() != 0 {
+		t.Errorf("Silent 级别不应输出日志，实际 %d 条", logs.Len())
+	}
+	if g.LogMode(logger.Info).(*GormLogger).logLevel != logger.Info {
+		t.Error("LogMode 应返回使用新级别的副本")
+	}
+}
+
+// TestGormLoggerContextLevel context 中的覆盖值只作用于使用该 context 的调用，不修改实例级别
+func TestGormLoggerContextLevel(t *testing.T) {
+	g, logs := newObservedGormLogger(config.GormLogConfig{Level: "error"})
+
+	ctx := WithGormLogLevel(context.Background(), logger.Info)
+	g.Trace(ctx, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, nil)
+	if logs.FilterMessage("SQL query executed").Len() != 1 {
+		t.Error("覆盖为 Info 的 context 应输出查询日志")
+	}
+
+	traceQuery(g, time.Millisecond, nil)
+	if logs.Len() != 1 {
+		t.Errorf("不带覆盖值的查询应按实例级别 (error) 处理，实际共 %d 条日志", logs.Len())
+	}
+	if g.levelFor(context.Background()) != logger.Error {
+		t.Error("实例级别不应被修改")
+	}
+
+	silenced := WithGormLogLevel(context.Background(), logger.Silent)
+	g.Trace(silenced, time.Now(), func() (string, int64) { return "SELECT 1", 1 }, errors.New("boom"))
+	if logs.Len() != 1 {
+		t.Error("覆盖为 Silent 的 context 不应输出日志")
+	}
+}
@@ -1,24 +1,132 @@
 package middleware
 
 import (
+	"context"
+	"net/http"
+
+	"github.com/Xushengqwer/go-common/core/identity"
+	"github.com/Xushengqwer/go-common/response"
+
 	"github.com/gin-gonic/gin"
 )
 
-func UserContextMiddleware() gin.HandlerFunc {
+// 网关转发给下游服务的用户身份请求头 (与 core/identity 的签名请求头保持一致)
+const (
+	HeaderUserID     = identity.HeaderUserID
+	HeaderUserRole   = identity.HeaderUserRole
+	HeaderUserStatus = identity.HeaderUserStatus
+	HeaderPlatform   = identity.HeaderPlatform
+)
+
+// userContextKey 是 UserContext 在 Gin Context 中的键名
+const userContextKey = "UserContext"
+
+// UserContext 是从网关请求头解析并校验后的当前用户身份
+// 定义在 core/identity 中，tracing.ExtractUserBaggage 还原的身份与请求头解析的身份是同一类型
+type UserContext = identity.UserContext
+
+// UserContextOption 配置 UserContextMiddleware
+type UserContextOption func(*userContextOptions)
+
+type userContextOptions struct {
+	strict   bool
+	verifier *identity.Verifier
+}
+
+// WithStrictUserContext 开启严格模式: 身份缺失或无效的请求直接返回 401 (ErrCodeClientUnauthorized)
+func WithStrictUserContext() UserContextOption {
+	return func(o *userContextOptions) {
+		o.strict = true
+	}
+}
+
+// WithIdentityVerifier 要求身份请求头带有网关的 HMAC 签名 (core/identity)
+// 携带了身份或签名请求头但签名缺失、密钥未知、超出重放窗口或不匹配的请求直接返回 401，不会退化为匿名请求；
+// 完全没有身份请求头的请求仍按匿名请求处理 (严格模式下返回 401)。
+func WithIdentityVerifier(v *identity.Verifier) UserContextOption {
+	return func(o *userContextOptions) {
+		o.verifier = v
+	}
+}
+
+// UserContextMiddleware 从网关请求头解析当前用户身份
+// - X-User-ID、X-User-Role、X-User-Status 必填，X-Platform 可选；角色和状态只接受 enums 中的名称 (e.g., "admin"、"active")，缺失、数值或无法识别的值视为身份无效
+// - 解析成功后 UserContext 同时写入 Gin Context 和 c.Request.Context() (constants.UserContextKey)，
+// 各字段的字符串形式写入 constants.UserIDKey / RoleKey / StatusKey / PlatformKey，并保留 Gin Context 中的 "UserID"/"Role"/"Status"/"Platform"
+// - 默认模式下身份缺失或无效的请求按匿名请求继续处理 (不写入任何身份信息)；严格模式 (WithStrictUserContext) 下返回 401
+// - 配置 WithIdentityVerifier 后只信任网关签名过的身份请求头，防止直接访问服务的客户端伪造身份
+//
+// 通过 CurrentUser(ctx) 读取当前用户。
+func UserContextMiddleware(opts ...UserContextOption) gin.HandlerFunc {
+	var o userContextOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(c *gin.Context) {
-		// 从 HTTP 头获取用户信息
-		userID := c.GetHeader("X-User-ID")
-		role := c.GetHeader("X-User-Role")
-		status := c.GetHeader("X-User-Status")
-		platform := c.GetHeader("X-Platform")
-
-		// 存入 Context
-		c.Set("UserID", userID)
-		c.Set("Role", role)
-		c.Set("Status", status)
-		c.Set("Platform", platform)
+		if o.verifier != nil && hasIdentityHeaders(c.Request.Header) {
+			if _, err := o.verifier.Verify(c.Request.Header); err != nil {
+				_ = c.Error(err)
+				response.RespondError(c, http.StatusUnauthorized, response.ErrCodeClientUnauthorized, "身份签名校验失败")
+				c.Abort()
+				return
+			}
+		}
+
+		user, err := identity.Parse(identity.FromHeader(c.Request.Header))
+		if err != nil {
+			if o.strict {
+				response.RespondError(c, http.StatusUnauthorized, response.ErrCodeClientUnauthorized, "身份信息缺失或无效")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		setUserContext(c, user)
 
 		// 继续处理请求
 		c.Next()
 	}
 }
+
+// CurrentUser 返回当前请求的用户身份，ctx 可以是 *gin.Context 或 c.Request.Context() (及其派生 context)
+// 第二个返回值为 false 表示匿名请求 (未经过 UserContextMiddleware 或身份无效)。
+// 通过 tracing.ExtractUserBaggage 还原了身份的 context 同样可以使用。
+func CurrentUser(ctx context.Context) (*UserContext, bool) {
+	if c, ok := ctx.(*gin.Context); ok {
+		if v, exists := c.Get(userContextKey); exists {
+			user, ok := v.(*UserContext)
+			return user, ok
+		}
+		if c.Request == nil {
+			return nil, false
+		}
+		ctx = c.Request.Context()
+	}
+	return identity.UserFromContext(ctx)
+}
+
+// setUserContext 把用户身份写入 Gin Context 和 c.Request.Context()
+func setUserContext(c *gin.Context, user *UserContext) {
+	role, status, platform := user.Role.String(), user.Status.String(), string(user.Platform)
+
+	c.Set(userContextKey, user)
+	c.Set("UserID", user.UserID)
+	c.Set("Role", role)
+	c.Set("Status", status)
+	c.Set("Platform", platform)
+
+	c.Request = c.Request.WithContext(identity.ContextWithUser(c.Request.Context(), user))
+}
+
+// hasIdentityHeaders 判断请求是否携带了任何身份或签名请求头
+func hasIdentityHeaders(h http.Header) bool {
+	for _, k := range []string{HeaderUserID, HeaderUserRole, HeaderUserStatus, HeaderPlatform, identity.HeaderSignature} {
+		if h.Get(k) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core/identity"
+	"github.com/Xushengqwer/go-common/models/enums"
+	"github.com/Xushengqwer/go-common/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newUserContextRouter 返回挂载 UserContextMiddleware 的路由，处理程序把当前用户写入 *got
+func newUserContextRouter(got **UserContext, opts ...UserContextOption) *gin.Engine {
+	r := gin.New()
+	r.Use(UserContextMiddleware(opts...))
+	r.GET("/", func(c *gin.Context) {
+		*got, _ = CurrentUser(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func identityRequest(role, status string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(HeaderUserID, "u-1")
+	if role != "" {
+		req.Header.Set(HeaderUserRole, role)
+	}
+	if status != "" {
+		req.Header.Set(HeaderUserStatus, status)
+	}
+	return req
+}
+
+// TestUserContextMiddleware 缺少状态或角色无效的身份不会被当作活跃用户或管理员
+func TestUserContextMiddleware(t *testing.T) {
+	cases := []struct {
+		name, role, status string
+	}{
+		{"缺少状态", "user", ""},
+		{"缺少角色", "", "active"},
+		{"数值角色", "0", "active"},
+		{"未知角色", "superuser", "active"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var user *UserContext
+			w := serve(newUserContextRouter(&user), identityRequest(tc.role, tc.status))
+			if w.Code != http.StatusOK || user != nil {
+				t.Errorf("默认模式应按匿名请求处理，实际状态码 %d，用户 %+v", w.Code, user)
+			}
+
+			user = nil
+			w = serve(newUserContextRouter(&user, WithStrictUserContext()), identityRequest(tc.role, tc.status))
+			if w.Code != http.StatusUnauthorized || user != nil {
+				t.Errorf("严格模式应返回 401，实际状态码 %d，用户 %+v", w.Code, user)
+			}
+		})
+	}
+
+	var user *UserContext
+	w := serve(newUserContextRouter(&user, WithStrictUserContext()), identityRequest("admin", "active"))
+	if w.Code != http.StatusOK || user == nil || !user.IsAdmin() || user.Status != enums.StatusActive {
+		t.Errorf("有效身份应被解析，实际状态码 %d，用户 %+v", w.Code, user)
+	}
+}
+
+// TestUserContextMiddlewareIdentityVerifier 配置 WithIdentityVerifier 后伪造或被篡改的身份返回 401，不会到达处理程序
+func TestUserContextMiddlewareIdentityVerifier(t *testing.T) {
+	signing := config.IdentitySigningConfig{KeyID: "k1", Keys: map[string]string{"k1": strings.Repeat("a", 32)}}
+	signer, err := identity.NewSigner(signing)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	verifier, err := identity.NewVerifier(signing)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	signed := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		signer.Sign(req.Header, identity.Identity{UserID: "u-1", Role: "user", Status: "active"})
+		return req
+	}
+
+	forged := map[string]*http.Request{
+		"未签名的身份":  identityRequest("admin", "active"),
+		"篡改角色":    signed(),
+		"只有签名请求头": httptest.NewRequest(http.MethodGet, "/", nil),
+	}
+	forged["篡改角色"].Header.Set(HeaderUserRole, "admin")
+	forged["只有签名请求头"].Header.Set(identity.HeaderSignature, "forged")
+
+	for name, req := range forged {
+		t.Run(name, func(t *testing.T) {
+			reached := false
+			r := gin.New()
+			r.Use(UserContextMiddleware(WithIdentityVerifier(verifier)))
+			r.GET("/", func(c *gin.Context) { reached = true })
+			w := serve(r, req)
+			if w.Code != http.StatusUnauthorized || decodeAPIResponse(t, w).Code != response.ErrCodeClientUnauthorized {
+				t.Errorf("应返回 401 和 ErrCodeClientUnauthorized，实际 %d %s", w.Code, w.Body.String())
+			}
+			if reached {
+				t.Error("校验失败的请求不应到达处理程序")
+			}
+		})
+	}
+
+	var user *UserContext
+	w := serve(newUserContextRouter(&user, WithIdentityVerifier(verifier)), signed())
+	if w.Code != http.StatusOK || user == nil || user.UserID != "u-1" || user.Role != enums.RoleUser {
+		t.Errorf("签名有效的身份应被解析，实际状态码 %d，用户 %+v", w.Code, user)
+	}
+
+	user = nil
+	w = serve(newUserContextRouter(&user, WithIdentityVerifier(verifier)), httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || user != nil {
+		t.Errorf("没有身份请求头的请求应按匿名处理，实际状态码 %d，用户 %+v", w.Code, user)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StatusClientClosedRequest 是客户端在响应写出之前断开连接时记录的状态码 (沿用 Nginx 的 499)
+// 这个状态码只用于日志、链路和指标，不会写到真正的 ResponseWriter 上，外层中间件通过 ResponseStatus 读取。
+const StatusClientClosedRequest = 499
+
+// clientClosedKey 是超时中间件在 Gin Context 中标记客户端已断开的键名
+const clientClosedKey = "clientClosed"
+
+// ResponseStatus 返回用于日志和链路的响应状态码: 客户端在响应写出之前断开时返回 StatusClientClosedRequest，
+// 否则返回 c.Writer.Status()
+func ResponseStatus(c *gin.Context) int {
+	if c.GetBool(clientClosedKey) {
+		return StatusClientClosedRequest
+	}
+	return c.Writer.Status()
+}
+
+// meterName 是中间件指标使用的 Meter 名称
+const meterName = "github.com/Xushengqwer/go-common/middleware"
+
+// 客户端断开时记录在 Span 和指标上的属性键
+const (
+	requestCancelledKey = attribute.Key("http.request.cancelled")
+	httpMethodKey       = attribute.Key("http.request.method")
+	httpRouteKey        = attribute.Key("http.route")
+)
+
+// clientClosedCounter 统计客户端提前断开的请求数
+// 使用全局 MeterProvider 的代理 Meter，即使中间件先于 MeterProvider 初始化也能正常上报
+var clientClosedCounter, _ = otel.Meter(meterName).Int64Counter("http.server.request.client_closed",
+	metric.WithDescription("客户端在响应写出之前断开连接的请求数"), metric.WithUnit("{request}"))
+
+// recordClientClosed 把客户端断开记录到当前 Span (事件 + http.request.cancelled 属性) 和指标上
+// 客户端主动放弃不是服务端错误，因此不会把 Span 状态标记为 Error。
+func recordClientClosed(ctx context.Context, method, route string) {
+	attrs := []attribute.KeyValue{httpMethodKey.String(method), httpRouteKey.String(route)}
+
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(requestCancelledKey.Bool(true))
+	span.AddEvent("http.client_closed", trace.WithAttributes(attrs...))
+
+	if clientClosedCounter != nil {
+		clientClosedCounter.Add(context.WithoutCancel(ctx), 1, metric.WithAttributes(attrs...))
+	}
+}
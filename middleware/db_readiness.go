@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Xushengqwer/go-common/core/database"
+	"github.com/Xushengqwer/go-common/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DBReadinessHandler 返回一个 Gin 就绪检查处理器: collector 中所有连接池都能 Ping 通时返回 200，否则返回 503
+// 失败原因通过 c.Error 交给日志中间件记录，不会返回给客户端。
+// 例如: router.GET("/readyz", middleware.DBReadinessHandler(collector))
+func DBReadinessHandler(collector *database.StatsCollector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := collector.Ready(c.Request.Context()); err != nil {
+			_ = c.Error(err)
+			response.RespondError(c, http.StatusServiceUnavailable, response.ErrCodeServerInternal, "数据库不可用")
+			return
+		}
+		response.RespondSuccess(c, "ok")
+	}
+}
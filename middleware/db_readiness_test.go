@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// TestDBReadinessHandler 连接池可用时返回 200，关闭后返回 503 且不暴露内部错误
+func TestDBReadinessHandler(t *testing.T) {
+	db, err := database.Open(config.DatabaseConfig{PrimaryDSN: filepath.Join(t.TempDir(), "ready.db")}, nil, database.WithDialector(sqlite.Open))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	collector, err := database.NewStatsCollector(newTestLogger(t), database.WithMeterProvider(noop.NewMeterProvider()))
+	if err != nil {
+		t.Fatalf("NewStatsCollector: %v", err)
+	}
+	t.Cleanup(func() { _ = collector.Stop() })
+	if err := collector.Register("main", db); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var errs []string
+	r := gin.New()
+	r.GET("/readyz", func(c *gin.Context) {
+		c.Next()
+		errs = c.Errors.Errors()
+	}, DBReadinessHandler(collector))
+
+	if w := serve(r, httptest.NewRequest(http.MethodGet, "/readyz", nil)); w.Code != http.StatusOK {
+		t.Fatalf("连接池可用时应返回 200，实际 %d", w.Code)
+	}
+
+	if err := database.Close(db); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("连接池关闭后应返回 503，实际 %d", w.Code)
+	}
+	if len(errs) != 1 {
+		t.Errorf("失败原因应通过 c.Error 记录，实际 %v", errs)
+	}
+	if body := w.Body.String(); body == "" || strings.Contains(body, "closed") || strings.Contains(body, "main") {
+		t.Errorf("响应不应包含内部错误信息: %s", body)
+	}
+}
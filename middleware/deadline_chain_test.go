@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/core/deadline"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hop 是调用链中的一个服务，记录处理程序看到的截止时间
+type hop struct {
+	srv      *httptest.Server
+	deadline chan time.Time
+}
+
+// newHop 启动一个带超时中间件的服务，next 为 nil 时处理程序一直等到 context 结束
+func newHop(t *testing.T, timeout, margin time.Duration, next *hop, leafDone chan<- error) *hop {
+	t.Helper()
+	h := &hop{deadline: make(chan time.Time, 1)}
+	client := &http.Client{Transport: deadline.NewTransport(nil, margin)}
+
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), timeout))
+	r.GET("/", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		d, _ := ctx.Deadline()
+		h.deadline <- d
+
+		if next == nil {
+			<-ctx.Done()
+			leafDone <- ctx.Err()
+			return
+		}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, next.srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			_ = c.Error(err)
+			c.Status(http.StatusBadGateway)
+			return
+		}
+		resp.Body.Close()
+		c.Status(resp.StatusCode)
+	})
+	h.srv = httptest.NewServer(r)
+	t.Cleanup(h.srv.Close)
+	return h
+}
+
+// TestDeadlineChainCancellation 三跳调用链 A -> B -> C: 截止时间逐跳扣除余量向下传递，
+// 最下游先于上游超时，504 沿调用链返回，整条链在入口服务自己的超时之前结束
+func TestDeadlineChainCancellation(t *testing.T) {
+	const (
+		timeout = 300 * time.Millisecond
+		margin  = 30 * time.Millisecond
+	)
+	leafDone := make(chan error, 1)
+	c := newHop(t, time.Minute, margin, nil, leafDone)
+	b := newHop(t, time.Minute, margin, c, nil)
+	a := newHop(t, timeout, margin, b, nil)
+
+	start := time.Now()
+	resp, err := http.Get(a.srv.URL)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	resp.Body.Close()
+	elapsed := time.Since(start)
+
+	dA, dB, dC := <-a.deadline, <-b.deadline, <-c.deadline
+	if dA.IsZero() || dB.IsZero() || dC.IsZero() {
+		t.Fatalf("每一跳都应带有截止时间: A=%v B=%v C=%v", dA, dB, dC)
+	}
+	// 请求头精度为毫秒，允许 1ms 的截断误差
+	if gap := dA.Sub(dB); gap < margin-time.Millisecond {
+		t.Errorf("B 的截止时间应比 A 早至少 %v，实际早 %v", margin, gap)
+	}
+	if gap := dB.Sub(dC); gap < margin-time.Millisecond {
+		t.Errorf("C 的截止时间应比 B 早至少 %v，实际早 %v", margin, gap)
+	}
+
+	if err := <-leafDone; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("C 应因截止时间到达而结束，实际 %v", err)
+	}
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("入口服务应返回 504，实际 %d", resp.StatusCode)
+	}
+	if elapsed >= timeout {
+		t.Errorf("下游的 504 应在入口服务自身超时 (%v) 之前返回，实际耗时 %v", timeout, elapsed)
+	}
+}
+
+// TestDeadlineChainExpiredBeforeCall 剩余时间不足余量时出站调用不会发出
+func TestDeadlineChainExpiredBeforeCall(t *testing.T) {
+	leafDone := make(chan error, 1)
+	c := newHop(t, time.Minute, 0, nil, leafDone)
+	b := newHop(t, 20*time.Millisecond, 50*time.Millisecond, c, nil)
+
+	resp, err := http.Get(b.srv.URL)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("剩余时间不足时 B 的出站调用应直接失败，实际 %d", resp.StatusCode)
+	}
+	select {
+	case d := <-c.deadline:
+		t.Errorf("请求不应到达 C (截止时间 %v)", d)
+	default:
+	}
+}
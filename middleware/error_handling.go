@@ -1,50 +1,184 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/Xushengqwer/go-common/constants"
 	"github.com/Xushengqwer/go-common/core"
 	"github.com/Xushengqwer/go-common/response"
 	"net/http"
 	"runtime/debug"
+	"strings"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// PanicInfo 是传给 PanicHook 的 panic 详情
+type PanicInfo struct {
+	Value   any    // recover() 得到的值
+	Stack   []byte // panic 时的堆栈
+	Method  string
+	Path    string
+	TraceID string
+	UserID  string
+}
+
+// PanicHook 在处理程序 panic (连接断开引起的除外) 时同步调用，可用于告警
+// 钩子不应阻塞请求，耗时的操作 (例如发送告警) 应在钩子内另起 goroutine；钩子自身的 panic 会被捕获并记录。
+type PanicHook func(ctx context.Context, info PanicInfo)
+
+// ErrorHandlingOption 配置 ErrorHandlingMiddleware
+type ErrorHandlingOption func(*errorHandlingOptions)
+
+type errorHandlingOptions struct {
+	panicHook PanicHook
+}
+
+// WithPanicHook 设置 panic 钩子
+func WithPanicHook(hook PanicHook) ErrorHandlingOption {
+	return func(o *errorHandlingOptions) {
+		o.panicHook = hook
+	}
+}
+
 // ErrorHandlingMiddleware 定义 Gin 的全局错误处理中间件，用于捕获和处理 Panic
 // - 使用自定义的 response.RespondError 进行标准化错误响应
-// - 输入: logger ZapLogger 实例，用于记录错误日志
+// - 连接已断开 (broken pipe / connection reset) 引起的 panic 只记录警告，不再向连接写入
+// - 响应已经 (部分) 写出时不再写入 500，避免重复写响应
+// - panic 会作为 exception 事件记录到当前 Span 上，日志中包含 trace_id 和 user_id
+// - 输入: logger ZapLogger 实例，用于记录错误日志；opts 可选配置 (例如 WithPanicHook)
 // - 输出: gin.HandlerFunc 中间件函数
-func ErrorHandlingMiddleware(logger *core.ZapLogger) gin.HandlerFunc {
+func ErrorHandlingMiddleware(logger *core.ZapLogger, opts ...ErrorHandlingOption) gin.HandlerFunc {
+	var o errorHandlingOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(c *gin.Context) {
 		// 1. 设置 Panic 捕获逻辑
 		defer func() {
-			if err := recover(); err != nil {
-				// 2. 记录 Panic 错误日志
-				logger.Error("Panic recovered",
-					zap.Any("error", err),                           // 错误内容
-					zap.String("errorType", fmt.Sprintf("%T", err)), // 错误类型
-					zap.String("stack", string(debug.Stack())),      // 堆栈跟踪
-					zap.String("path", c.Request.URL.Path),          // 请求路径
-					zap.String("method", c.Request.Method),          // 请求方法
-					zap.String("clientIP", c.ClientIP()),            // 客户端 IP
+			err := recover()
+			if err == nil {
+				return
+			}
+			// 异常路径上才取堆栈
+			stack := debug.Stack()
+			ctx := c.Request.Context()
+			span := trace.SpanFromContext(ctx)
+			traceID := requestTraceID(c, span)
+			userID := requestUserID(c)
+
+			// 2. 连接已断开: 写入没有意义，也不算服务端故障
+			if isBrokenPipe(err) {
+				logger.Warn("连接已断开，放弃写入响应",
+					zap.Any("error", err),
+					zap.String("trace_id", traceID),
+					zap.String("path", c.Request.URL.Path),
+					zap.String("method", c.Request.Method),
+					zap.String("clientIP", c.ClientIP()),
 				)
+				if e, ok := err.(error); ok {
+					_ = c.Error(e)
+				}
+				c.Abort()
+				return
+			}
+
+			// 3. 记录 Panic 错误日志
+			logger.Error("Panic recovered",
+				zap.Any("error", err),                           // 错误内容
+				zap.String("errorType", fmt.Sprintf("%T", err)), // 错误类型
+				zap.String("stack", string(stack)),              // 堆栈跟踪
+				zap.String("trace_id", traceID),                 // OTel Trace ID
+				zap.String("user_id", userID),                   // 当前用户
+				zap.String("path", c.Request.URL.Path),          // 请求路径
+				zap.String("method", c.Request.Method),          // 请求方法
+				zap.String("clientIP", c.ClientIP()),            // 客户端 IP
+			)
 
-				// 3. 返回标准化的错误响应
-				// - 使用自定义的 RespondError 函数
+			// 4. 记录到 Span: exception 事件 + Error 状态
+			// 直接写 exception 事件: span.RecordError 会用包装错误的类型覆盖 exception.type
+			span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+				semconv.ExceptionType(fmt.Sprintf("%T", err)),
+				semconv.ExceptionMessage(fmt.Sprintf("panic: %v", err)),
+				semconv.ExceptionStacktrace(string(stack)),
+				semconv.ExceptionEscaped(false),
+			))
+			span.SetStatus(codes.Error, "panic")
+
+			// 5. 调用告警钩子
+			if o.panicHook != nil {
+				runPanicHook(logger, o.panicHook, ctx, PanicInfo{
+					Value:   err,
+					Stack:   stack,
+					Method:  c.Request.Method,
+					Path:    c.Request.URL.Path,
+					TraceID: traceID,
+					UserID:  userID,
+				})
+			}
+
+			// 6. 返回标准化的错误响应 (响应已经写出时只中止后续处理)
+			if !c.Writer.Written() {
 				response.RespondError(
 					c,                              // Gin 上下文
 					http.StatusInternalServerError, // HTTP 状态码 500
 					response.ErrCodeServerInternal, // 自定义内部服务器错误码
-					"服务器内部错误，请稍后再试。", // 对用户友好的错误消息
+					internalErrorMessage,           // 对用户友好的错误消息
 				)
-				// c.Abort() 确保在此中间件之后不再调用其他处理程序
-				// RespondError 已经写入了响应，但 Abort 明确停止链式调用
-				c.Abort()
 			}
+			// c.Abort() 确保在此中间件之后不再调用其他处理程序
+			c.Abort()
 		}()
 
-		// 4. 继续处理后续请求
+		// 7. 继续处理请求
 		c.Next()
 	}
 }
+
+// runPanicHook 调用 panic 钩子，钩子自身的 panic 被捕获并记录，不影响错误响应的写出
+func runPanicHook(logger *core.ZapLogger, hook PanicHook, ctx context.Context, info PanicInfo) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("panic 钩子执行失败", zap.Any("error", r), zap.String("trace_id", info.TraceID))
+		}
+	}()
+	hook(ctx, info)
+}
+
+// isBrokenPipe 判断 panic 是否由客户端断开连接引起 (EPIPE / ECONNRESET)，http.ErrAbortHandler 同样视为连接中止
+func isBrokenPipe(v any) bool {
+	err, ok := v.(error)
+	if !ok {
+		return false
+	}
+	if errors.Is(err, http.ErrAbortHandler) || errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// 部分写入错误只保留了文本 (例如经过 fmt.Errorf("%v") 包装)
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// requestTraceID 返回当前请求的 Trace ID: 优先使用 Span，其次使用 Gin Context 中的 constants.TraceIDKey
+func requestTraceID(c *gin.Context, span trace.Span) string {
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return c.GetString(constants.TraceIDKey)
+}
+
+// requestUserID 返回当前用户 ID: 优先使用 CurrentUser，其次读取 request context 中的 constants.UserIDKey
+func requestUserID(c *gin.Context) string {
+	if user, ok := CurrentUser(c); ok {
+		return user.UserID
+	}
+	userID, _ := c.Request.Context().Value(constants.UserIDKey).(string)
+	return userID
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/response"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestIsBrokenPipe 连接断开的错误 (包括被包装或只保留文本的) 才视为 broken pipe
+func TestIsBrokenPipe(t *testing.T) {
+	cases := []struct {
+		name string
+		v    any
+		want bool
+	}{
+		{"EPIPE", syscall.EPIPE, true},
+		{"ECONNRESET", syscall.ECONNRESET, true},
+		{"包装的 EPIPE", &os.SyscallError{Syscall: "write", Err: syscall.EPIPE}, true},
+		{"http.ErrAbortHandler", http.ErrAbortHandler, true},
+		{"只保留文本的 broken pipe", fmt.Errorf("write tcp: %v", "Broken pipe"), true},
+		{"只保留文本的 connection reset", errors.New("read: connection reset by peer"), true},
+		{"普通错误", errors.New("boom"), false},
+		{"非 error 值", "broken pipe", false},
+	}
+	for _, tc := range cases {
+		if got := isBrokenPipe(tc.v); got != tc.want {
+			t.Errorf("%s: isBrokenPipe = %v，期望 %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// newPanicRouter 返回带 ErrorHandlingMiddleware 的路由，每个请求都在一个可记录的 Span 中处理
+func newPanicRouter(t *testing.T, opts ...ErrorHandlingOption) (*gin.Engine, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx, span := tp.Tracer("test").Start(c.Request.Context(), c.FullPath())
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	r.Use(ErrorHandlingMiddleware(newTestLogger(t), opts...))
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	r.GET("/partial", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom after write")
+	})
+	r.GET("/broken-pipe", func(c *gin.Context) {
+		panic(&os.SyscallError{Syscall: "write", Err: syscall.EPIPE})
+	})
+	return r, recorder
+}
+
+// TestErrorHandlingMiddlewarePanic panic 返回 500，并在 Span 上记录 exception 事件和 Error 状态
+func TestErrorHandlingMiddlewarePanic(t *testing.T) {
+	r, recorder := newPanicRouter(t)
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("状态码 = %d，期望 500", w.Code)
+	}
+	if resp := decodeAPIResponse(t, w); resp.Code != response.ErrCodeServerInternal || resp.Message != internalErrorMessage {
+		t.Errorf("响应 = %+v", resp)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("应结束 1 个 Span，实际 %d", len(spans))
+	}
+	span := spans[0]
+	if span.Status().Code != codes.Error {
+		t.Errorf("Span 状态应为 Error，实际 %v", span.Status())
+	}
+	var found bool
+	for _, ev := range span.Events() {
+		if ev.Name != "exception" {
+			continue
+		}
+		found = true
+		attrs := map[string]string{}
+		for _, kv := range ev.Attributes {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		if attrs["exception.message"] != "panic: boom" || attrs["exception.type"] != "string" || attrs["exception.stacktrace"] == "" {
+			t.Errorf("exception 事件属性 = %v", attrs)
+		}
+	}
+	if !found {
+		t.Error("Span 上应记录 exception 事件")
+	}
+}
+
+// TestErrorHandlingMiddlewarePartialWrite 响应已部分写出时不再写入 500
+func TestErrorHandlingMiddlewarePartialWrite(t *testing.T) {
+	r, _ := newPanicRouter(t)
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/partial", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("已写出的响应不应被改写: %d %q", w.Code, w.Body.String())
+	}
+}
+
+// TestErrorHandlingMiddlewareBrokenPipe 连接断开引起的 panic 不写响应，也不调用钩子
+func TestErrorHandlingMiddlewareBrokenPipe(t *testing.T) {
+	var called bool
+	r, recorder := newPanicRouter(t, WithPanicHook(func(context.Context, PanicInfo) { called = true }))
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/broken-pipe", nil))
+	if w.Body.Len() != 0 {
+		t.Errorf("连接已断开时不应写入响应体，实际 %q", w.Body.String())
+	}
+	if called {
+		t.Error("连接断开引起的 panic 不应触发钩子")
+	}
+	if spans := recorder.Ended(); len(spans) == 1 && spans[0].Status().Code == codes.Error {
+		t.Error("连接断开不应把 Span 标记为 Error")
+	}
+}
+
+// TestErrorHandlingMiddlewarePanicHook 钩子收到 panic 详情，钩子自身的 panic 被捕获，仍然写出 500
+func TestErrorHandlingMiddlewarePanicHook(t *testing.T) {
+	var got PanicInfo
+	r, recorder := newPanicRouter(t, WithPanicHook(func(ctx context.Context, info PanicInfo) {
+		got = info
+	}))
+
+	serve(r, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if got.Value != "boom" || got.Method != http.MethodGet || got.Path != "/panic" || len(got.Stack) == 0 {
+		t.Errorf("钩子收到的详情 = %+v", got)
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || got.TraceID != spans[0].SpanContext().TraceID().String() {
+		t.Errorf("钩子收到的 TraceID = %q，应为当前 Span 的 Trace ID", got.TraceID)
+	}
+
+	r, _ = newPanicRouter(t, WithPanicHook(func(context.Context, PanicInfo) {
+		panic("hook failed")
+	}))
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("钩子 panic 后仍应返回 500，实际 %d", w.Code)
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+	"github.com/Xushengqwer/go-common/core"
+	"github.com/Xushengqwer/go-common/core/tracing"
+	"github.com/Xushengqwer/go-common/response"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// internalErrorMessage 是未映射的错误返回给客户端的消息，内部错误信息只记录在日志中
+const internalErrorMessage = "服务器内部错误，请稍后再试。"
+
+// ErrorMapping 描述一个错误对应的 HTTP 状态码、业务错误码和对外消息
+// Message 为空时使用注册时的目标错误本身的消息 (commonerrors 中的哨兵错误消息都可以直接展示给用户)。
+type ErrorMapping struct {
+	Status  int
+	Code    int
+	Message string
+}
+
+// errorMappingEntry 是映射表中的一项
+type errorMappingEntry struct {
+	target  error
+	mapping ErrorMapping
+}
+
+// ErrorMapper 是错误到 API 响应的映射表，并发安全
+// - *commonerrors.AppError 直接使用自身的状态码、错误码和消息，优先于映射表
+// - 其余错误按 errors.Is 匹配映射表，后注册的映射优先 (可以覆盖默认映射)
+type ErrorMapper struct {
+	mu      sync.RWMutex
+	entries []errorMappingEntry
+}
+
+// NewErrorMapper 创建包含 commonerrors 哨兵错误默认映射的映射表
+func NewErrorMapper() *ErrorMapper {
+	m := &ErrorMapper{}
+	m.Register(commonerrors.ErrRepoNotFound, ErrorMapping{Status: http.StatusNotFound, Code: response.ErrCodeClientResourceNotFound})
+	m.Register(commonerrors.ErrUserNotLoggedIn, ErrorMapping{Status: http.StatusUnauthorized, Code: response.ErrCodeClientUnauthorized})
+	m.Register(commonerrors.ErrVersionConflict, ErrorMapping{Status: http.StatusConflict, Code: response.ErrCodeClientConflict})
+	m.Register(commonerrors.ErrServiceBusy, ErrorMapping{Status: http.StatusServiceUnavailable, Code: response.ErrCodeServerBusy})
+	m.Register(commonerrors.ErrSystemError, ErrorMapping{Status: http.StatusInternalServerError, Code: response.ErrCodeServerInternal})
+	m.Register(commonerrors.ErrThirdPartyServiceError, ErrorMapping{Status: http.StatusBadGateway, Code: response.ErrCodeThirdPartyServiceError})
+	m.Register(context.DeadlineExceeded, ErrorMapping{Status: http.StatusGatewayTimeout, Code: response.ErrCodeServerTimeout, Message: "请求超时，请稍后重试。"})
+	return m
+}
+
+// DefaultErrorMapper 是 ErrorMappingMiddleware 未指定映射表时使用的全局映射表
+// 它是进程内共享的可变状态: 注册的映射对所有使用它的路由生效，测试或需要隔离的场景请使用 NewErrorMapper 创建独立的映射表。
+var DefaultErrorMapper = NewErrorMapper()
+
+// RegisterErrorMapping 向 DefaultErrorMapper 注册一个错误映射
+// 必须在服务启动阶段 (开始处理请求之前) 调用: 运行中注册虽然不会产生数据竞争，但同一个错误在注册前后的请求中会得到不同的响应。
+func RegisterErrorMapping(target error, mapping ErrorMapping) {
+	DefaultErrorMapper.Register(target, mapping)
+}
+
+// Register 注册一个错误映射，target 为 nil 时忽略
+func (m *ErrorMapper) Register(target error, mapping ErrorMapping) {
+	if target == nil {
+		return
+	}
+	if mapping.Message == "" {
+		mapping.Message = target.Error()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, errorMappingEntry{target: target, mapping: mapping})
+}
+
+// Map 返回错误对应的映射，第二个返回值为 false 表示没有匹配的映射
+func (m *ErrorMapper) Map(err error) (ErrorMapping, bool) {
+	if err == nil {
+		return ErrorMapping{}, false
+	}
+	var appErr *commonerrors.AppError
+	if errors.As(err, &appErr) {
+		mapping := ErrorMapping{Status: appErr.Status, Code: appErr.Code, Message: appErr.Message}
+		if mapping.Status == 0 {
+			mapping.Status = http.StatusInternalServerError
+		}
+		return mapping, true
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if errors.Is(err, m.entries[i].target) {
+			return m.entries[i].mapping, true
+		}
+	}
+	return ErrorMapping{}, false
+}
+
+// ErrorMappingMiddleware 在处理程序返回后检查 c.Errors，把最后一个错误映射为标准的 APIResponse
+// - 处理程序只需要 c.Error(err) 并返回，不必自己写响应
+// - 已经写出响应 (包括超时的 504) 或客户端已断开 (499) 时不再写入，保证每个请求只有一个响应
+// - 未映射的错误返回 500，对外只给出通用消息，内部错误信息只记录在日志和链路中
+// - mapper 为 nil 时使用 DefaultErrorMapper
+//
+// 需要注册在 RequestTimeoutMiddleware 之后，这样写出的错误响应同样受超时保护，RequestLoggerMiddleware 也能记录映射后的状态码。
+func ErrorMappingMiddleware(logger *core.ZapLogger, mapper *ErrorMapper) gin.HandlerFunc {
+	if mapper == nil {
+		mapper = DefaultErrorMapper
+	}
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 {
+			return
+		}
+		err := c.Errors.Last().Err
+		if c.Writer.Written() || ResponseStatus(c) == StatusClientClosedRequest {
+			logger.Debug("响应已写出，忽略处理程序返回的错误",
+				zap.Error(err),
+				zap.String("path", c.Request.URL.Path),
+			)
+			return
+		}
+
+		mapping, ok := mapper.Map(err)
+		if !ok {
+			mapping = ErrorMapping{
+				Status:  http.StatusInternalServerError,
+				Code:    response.ErrCodeServerInternal,
+				Message: internalErrorMessage,
+			}
+		}
+
+		fields := []zap.Field{
+			zap.Error(err),
+			zap.Int("status", mapping.Status),
+			zap.Int("code", mapping.Code),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("method", c.Request.Method),
+		}
+		if mapping.Status >= http.StatusInternalServerError {
+			logger.Error("请求处理失败", fields...)
+		} else {
+			logger.Debug("请求处理返回业务错误", fields...)
+		}
+		tracing.RecordError(trace.SpanFromContext(c.Request.Context()), err)
+
+		response.RespondError(c, mapping.Status, mapping.Code, mapping.Message)
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+	"github.com/Xushengqwer/go-common/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errNotMapped 是没有注册映射的内部错误
+var errNotMapped = errors.New("dial tcp 10.0.0.1:3306: connection refused")
+
+// TestErrorMapperMap 检查 AppError 优先、后注册覆盖默认映射、包装错误按 errors.Is 匹配
+func TestErrorMapperMap(t *testing.T) {
+	m := NewErrorMapper()
+	m.Register(commonerrors.ErrRepoNotFound, ErrorMapping{Status: http.StatusGone, Code: 42, Message: "已下线"})
+
+	cases := []struct {
+		name string
+		err  error
+		want ErrorMapping
+		ok   bool
+	}{
+		{"默认映射", commonerrors.ErrVersionConflict, ErrorMapping{http.StatusConflict, response.ErrCodeClientConflict, commonerrors.ErrVersionConflict.Error()}, true},
+		{"后注册的映射覆盖默认映射", commonerrors.ErrRepoNotFound, ErrorMapping{http.StatusGone, 42, "已下线"}, true},
+		{"包装的哨兵错误", fmt.Errorf("get post: %w", commonerrors.ErrVersionConflict), ErrorMapping{http.StatusConflict, response.ErrCodeClientConflict, commonerrors.ErrVersionConflict.Error()}, true},
+		{"AppError 优先于映射表", commonerrors.WrapAppError(commonerrors.ErrRepoNotFound, http.StatusBadRequest, 7, "参数错误"), ErrorMapping{http.StatusBadRequest, 7, "参数错误"}, true},
+		{"包装的 AppError", fmt.Errorf("handler: %w", commonerrors.NewAppError(http.StatusForbidden, 8, "禁止访问")), ErrorMapping{http.StatusForbidden, 8, "禁止访问"}, true},
+		{"AppError 未设置状态码时为 500", commonerrors.NewAppError(0, 9, "失败"), ErrorMapping{http.StatusInternalServerError, 9, "失败"}, true},
+		{"未映射的错误", errNotMapped, ErrorMapping{}, false},
+		{"nil", nil, ErrorMapping{}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := m.Map(tc.err)
+			if ok != tc.ok || got != tc.want {
+				t.Errorf("Map = %+v, %v，期望 %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+
+	if got, _ := NewErrorMapper().Map(commonerrors.ErrRepoNotFound); got.Status != http.StatusNotFound {
+		t.Errorf("注册只应影响当前映射表，新映射表的 ErrRepoNotFound 状态码 = %d", got.Status)
+	}
+}
+
+// newErrorMappingRouter 返回只注册了 ErrorMappingMiddleware 的路由
+func newErrorMappingRouter(t *testing.T, mapper *ErrorMapper) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorMappingMiddleware(newTestLogger(t), mapper))
+	r.GET("/not-found", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("load post: %w", commonerrors.ErrRepoNotFound))
+	})
+	r.GET("/internal", func(c *gin.Context) {
+		_ = c.Error(errNotMapped)
+	})
+	r.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "done")
+		_ = c.Error(errNotMapped)
+	})
+	r.GET("/ok", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return r
+}
+
+// decodeAPIResponse 解析标准响应体
+func decodeAPIResponse(t *testing.T, w *httptest.ResponseRecorder) response.APIResponse[any] {
+	t.Helper()
+	var resp response.APIResponse[any]
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("响应体不是 APIResponse: %v, body=%s", err, w.Body.String())
+	}
+	return resp
+}
+
+// TestErrorMappingMiddleware 映射处理程序返回的错误，未映射的错误不向客户端暴露内部信息
+func TestErrorMappingMiddleware(t *testing.T) {
+	r := newErrorMappingRouter(t, NewErrorMapper())
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/not-found", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("包装的 ErrRepoNotFound 状态码 = %d，期望 404", w.Code)
+	}
+	if resp := decodeAPIResponse(t, w); resp.Code != response.ErrCodeClientResourceNotFound || resp.Message != commonerrors.ErrRepoNotFound.Error() {
+		t.Errorf("响应 = %+v", resp)
+	}
+
+	w = serve(r, httptest.NewRequest(http.MethodGet, "/internal", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("未映射的错误状态码 = %d，期望 500", w.Code)
+	}
+	if resp := decodeAPIResponse(t, w); resp.Code != response.ErrCodeServerInternal || resp.Message != internalErrorMessage {
+		t.Errorf("响应 = %+v", resp)
+	}
+	if strings.Contains(w.Body.String(), "10.0.0.1") {
+		t.Errorf("响应体不应包含内部错误信息: %s", w.Body.String())
+	}
+
+	w = serve(r, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("没有错误时不应改写响应: %d %q", w.Code, w.Body.String())
+	}
+}
+
+// TestErrorMappingMiddlewareAlreadyWritten 处理程序已写出响应时不再写入错误响应
+func TestErrorMappingMiddlewareAlreadyWritten(t *testing.T) {
+	w := serve(newErrorMappingRouter(t, NewErrorMapper()), httptest.NewRequest(http.MethodGet, "/written", nil))
+	if w.Code != http.StatusAccepted || w.Body.String() != "done" {
+		t.Errorf("已写出的响应不应被改写: %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 请求体采集的默认值
+const defaultCaptureMaxBodyBytes = 4096
+
+// redactedValue 是被脱敏的字段在日志中的替代值
+const redactedValue = "***"
+
+var (
+	defaultCaptureContentTypes = []string{"application/json"}
+	defaultCaptureRedactKeys   = []string{"password", "contact_info", "token"}
+)
+
+// requestCapture 是预处理后的 config.RequestCaptureConfig，由 RequestLoggerMiddleware 使用
+type requestCapture struct {
+	cfg           config.RequestCaptureConfig
+	maxBytes      int
+	contentTypes  []string            // 小写的媒体类型，以 "/*" 结尾表示匹配整个大类 (e.g., "text/*")
+	redactKeys    map[string]struct{} // 小写的键名
+	redactRe      *regexp.Regexp      // 无法解析的 (被截断的) JSON 使用正则脱敏标量值
+	redactNestRe  *regexp.Regexp      // 无法解析的 JSON 中值为对象或数组的脱敏键
+	routes        []routePattern
+	statusClasses map[int]struct{} // 状态码类别 (e.g., 4 表示 4xx)
+}
+
+// routePattern 是精确或前缀匹配的路由模式
+type routePattern struct {
+	path   string
+	prefix bool
+}
+
+// newRoutePatterns 解析路由模式列表，以 "*" 结尾表示前缀匹配
+func newRoutePatterns(paths []string) []routePattern {
+	patterns := make([]routePattern, 0, len(paths))
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			patterns = append(patterns, routePattern{path: strings.TrimSuffix(p, "*"), prefix: true})
+		} else {
+			patterns = append(patterns, routePattern{path: p})
+		}
+	}
+	return patterns
+}
+
+// newRequestCapture 根据配置创建采集器，没有开启任何采集项时返回 nil
+func newRequestCapture(cfg config.RequestCaptureConfig) *requestCapture {
+	if !cfg.Sizes && !cfg.Query && !cfg.Route && !cfg.RequestBody && !cfg.ResponseBody {
+		return nil
+	}
+	rc := &requestCapture{
+		cfg:        cfg,
+		maxBytes:   cfg.MaxBodyBytes,
+		routes:     newRoutePatterns(cfg.Routes),
+		redactKeys: make(map[string]struct{}),
+	}
+	if rc.maxBytes <= 0 {
+		rc.maxBytes = defaultCaptureMaxBodyBytes
+	}
+
+	contentTypes := cfg.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultCaptureContentTypes
+	}
+	for _, ct := range contentTypes {
+		rc.contentTypes = append(rc.contentTypes, strings.ToLower(strings.TrimSpace(ct)))
+	}
+
+	redactKeys := cfg.RedactKeys
+	if len(redactKeys) == 0 {
+		redactKeys = defaultCaptureRedactKeys
+	}
+	quoted := make([]string, 0, len(redactKeys))
+	for _, k := range redactKeys {
+		rc.redactKeys[strings.ToLower(k)] = struct{}{}
+		quoted = append(quoted, regexp.QuoteMeta(k))
+	}
+	keys := strings.Join(quoted, "|")
+	rc.redactRe = regexp.MustCompile(`(?i)("(?:` + keys + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[-\w.+]+)`)
+	rc.redactNestRe = regexp.MustCompile(`(?i)("(?:` + keys + `)"\s*:\s*)[\[{]`)
+
+	if len(cfg.StatusClasses) > 0 {
+		rc.statusClasses = make(map[int]struct{}, len(cfg.StatusClasses))
+		for _, sc := range cfg.StatusClasses {
+			if sc = strings.TrimSpace(sc); sc != "" && sc[0] >= '1' && sc[0] <= '5' {
+				rc.statusClasses[int(sc[0]-'0')] = struct{}{}
+			}
+		}
+	}
+	return rc
+}
+
+// routeAllowed 判断当前路由是否在请求/响应体的记录范围内
+func (rc *requestCapture) routeAllowed(c *gin.Context) bool {
+	if len(rc.routes) == 0 {
+		return true
+	}
+	path := routePath(c)
+	for _, p := range rc.routes {
+		if matchRoute(path, p.path, p.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusAllowed 判断状态码是否在请求/响应体的记录范围内
+func (rc *requestCapture) statusAllowed(status int) bool {
+	if rc.statusClasses == nil {
+		return true
+	}
+	_, ok := rc.statusClasses[status/100]
+	return ok
+}
+
+// contentTypeAllowed 判断 Content-Type 是否在记录范围内
+func (rc *requestCapture) contentTypeAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, ct := range rc.contentTypes {
+		if ct == mediaType || (strings.HasSuffix(ct, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(ct, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// start 在处理程序执行前包装请求体和 ResponseWriter，返回处理结束后生成日志字段的函数
+// 调用方负责在处理结束后还原 c.Writer
+func (rc *requestCapture) start(c *gin.Context) func() []zap.Field {
+	var reqBuf, respBuf *captureBuffer
+	bodies := (rc.cfg.RequestBody || rc.cfg.ResponseBody) && rc.routeAllowed(c)
+	// 记录大小时即使不记录请求体也需要统计实际读取的字节数 (分块传输时 ContentLength 为 -1)
+	if c.Request.Body != nil && c.Request.Body != http.NoBody && (rc.cfg.Sizes || (bodies && rc.cfg.RequestBody)) {
+		limit := 0
+		if bodies && rc.cfg.RequestBody && rc.contentTypeAllowed(c.ContentType()) {
+			limit = rc.maxBytes
+		}
+		reqBuf = &captureBuffer{limit: limit}
+		c.Request.Body = &captureReader{ReadCloser: c.Request.Body, buf: reqBuf}
+	}
+	if bodies && rc.cfg.ResponseBody {
+		respBuf = &captureBuffer{limit: rc.maxBytes}
+		c.Writer = &captureWriter{ResponseWriter: c.Writer, buf: respBuf}
+	}
+
+	return func() []zap.Field {
+		var fields []zap.Field
+		if rc.cfg.Route {
+			fields = append(fields, zap.String("http.route", c.FullPath()))
+		}
+		if rc.cfg.Query && c.Request.URL.RawQuery != "" {
+			fields = append(fields, zap.String("url.query", rc.redactForm(c.Request.URL.RawQuery)))
+		}
+		if rc.cfg.Sizes {
+			reqSize := c.Request.ContentLength
+			if reqBuf != nil {
+				reqSize = reqBuf.total
+			}
+			respSize := c.Writer.Size()
+			if respSize < 0 {
+				respSize = 0
+			}
+			fields = append(fields,
+				zap.Int64("http.request.body.size", max(reqSize, 0)),
+				zap.Int("http.response.body.size", respSize),
+			)
+		}
+		if !bodies || !rc.statusAllowed(ResponseStatus(c)) {
+			return fields
+		}
+		if reqBuf != nil && reqBuf.limit > 0 && reqBuf.total > 0 {
+			fields = append(fields, zap.String("http.request.body", rc.render(reqBuf, c.ContentType())))
+		}
+		if respBuf != nil && respBuf.total > 0 {
+			if ct := c.Writer.Header().Get("Content-Type"); rc.contentTypeAllowed(ct) {
+				fields = append(fields, zap.String("http.response.body", rc.render(respBuf, ct)))
+			}
+		}
+		return fields
+	}
+}
+
+// render 把采集到的请求/响应体脱敏并格式化为日志字符串，被截断时追加被截掉的字节数标记
+func (rc *requestCapture) render(buf *captureBuffer, contentType string) string {
+	data := buf.buf.Bytes()
+	truncated := buf.total > int64(len(data))
+	if truncated {
+		data = trimPartialRune(data)
+	}
+
+	var body string
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		body = rc.redactForm(string(data))
+	case strings.Contains(mediaType, "json"):
+		body = rc.redactJSON(data)
+	default:
+		body = string(data)
+	}
+	body = strings.ToValidUTF8(body, "?")
+	if truncated {
+		body = fmt.Sprintf("%s...[truncated %d bytes]", body, buf.total-int64(len(data)))
+	}
+	return body
+}
+
+// redactJSON 把 JSON 中 redactKeys 对应的值 (包括嵌套对象和数组) 替换为 "***"
+// 能完整解析时按结构脱敏；被截断或无效的 JSON 退化为用正则替换键后面的标量值，
+// 值为对象或数组时无法确定其结束位置，从该键开始丢弃之后的全部内容。
+func (rc *requestCapture) redactJSON(data []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		if out, err := json.Marshal(rc.redactValue(v)); err == nil {
+			return string(out)
+		}
+	}
+	out := rc.redactRe.ReplaceAllString(string(data), `${1}"`+redactedValue+`"`)
+	if loc := rc.redactNestRe.FindStringSubmatchIndex(out); loc != nil {
+		out = out[:loc[3]] + `"` + redactedValue + `"`
+	}
+	return out
+}
+
+// redactValue 递归脱敏解析后的 JSON 值
+func (rc *requestCapture) redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if _, ok := rc.redactKeys[strings.ToLower(k)]; ok {
+				val[k] = redactedValue
+			} else {
+				val[k] = rc.redactValue(child)
+			}
+		}
+	case []any:
+		for i, child := range val {
+			val[i] = rc.redactValue(child)
+		}
+	}
+	return v
+}
+
+// redactForm 脱敏查询字符串或 application/x-www-form-urlencoded 请求体，保留原始顺序，只替换需要脱敏的字段值
+func (rc *requestCapture) redactForm(raw string) string {
+	pairs := strings.Split(raw, "&")
+	for i, pair := range pairs {
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key := rawKey
+		if k, err := url.QueryUnescape(rawKey); err == nil {
+			key = k
+		}
+		if _, ok := rc.redactKeys[strings.ToLower(key)]; ok {
+			pairs[i] = rawKey + "=" + redactedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// trimPartialRune 去掉末尾不完整的多字节字符，避免在字符中间截断
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// captureBuffer 只保留前 limit 个字节，但统计写入的总字节数
+type captureBuffer struct {
+	buf   bytes.Buffer
+	limit int
+	total int64
+}
+
+func (b *captureBuffer) write(p []byte) {
+	b.total += int64(len(p))
+	if remaining := b.limit - b.buf.Len(); remaining > 0 {
+		b.buf.Write(p[:min(len(p), remaining)])
+	}
+}
+
+// captureReader 在处理程序读取请求体的同时采集内容，不会预先读取请求体，处理程序没有读取的部分不会被记录
+type captureReader struct {
+	io.ReadCloser
+	buf *captureBuffer
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.write(p[:n])
+	return n, err
+}
+
+// captureWriter 在写出响应的同时采集响应体
+type captureWriter struct {
+	gin.ResponseWriter
+	buf *captureBuffer
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.buf.write(data[:n])
+	return n, err
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.buf.write([]byte(s[:n]))
+	return n, err
+}
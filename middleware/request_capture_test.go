@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRedactJSON 完整的 JSON 按结构脱敏 (包括嵌套对象和数组)，键名不区分大小写
+func TestRedactJSON(t *testing.T) {
+	rc := newRequestCapture(config.RequestCaptureConfig{RequestBody: true})
+	cases := []struct {
+		name, in, want string
+	}{
+		{"顶层键", `{"name":"a","password":"p"}`, `{"name":"a","password":"***"}`},
+		{"嵌套对象", `{"user":{"Password":"p","age":3}}`, `{"user":{"Password":"***","age":3}}`},
+		{"数组中的对象", `{"items":[{"token":"t"},{"id":1}]}`, `{"items":[{"token":"***"},{"id":1}]}`},
+		{"值为对象的键", `{"contact_info":{"phone":"138"},"id":1}`, `{"contact_info":"***","id":1}`},
+	}
+	for _, tc := range cases {
+		if got := rc.redactJSON([]byte(tc.in)); got != tc.want {
+			t.Errorf("%s: redactJSON = %s，期望 %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestRedactJSONTruncated 无法解析的 (被截断的) JSON 退化为正则脱敏，值为对象时丢弃该键之后的内容
+func TestRedactJSONTruncated(t *testing.T) {
+	rc := newRequestCapture(config.RequestCaptureConfig{RequestBody: true})
+	cases := []struct {
+		name, in, want string
+	}{
+		{"字符串值", `{"password": "secret", "bio": "long te`, `{"password": "***", "bio": "long te`},
+		{"被截断的字符串值", `{"name":"a","token":"abc`, `{"name":"a","token":"***"`},
+		{"数字值", `{"token":12345,"x":[1,2`, `{"token":"***","x":[1,2`},
+		{"值为对象", `{"id":1,"contact_info":{"phone":"138","email":"a@b.c"},"bio":"x`, `{"id":1,"contact_info":"***"`},
+	}
+	for _, tc := range cases {
+		got := rc.redactJSON([]byte(tc.in))
+		if got != tc.want {
+			t.Errorf("%s: redactJSON = %s，期望 %s", tc.name, got, tc.want)
+		}
+		for _, secret := range []string{"secret", "abc", "12345", "138"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("%s: 脱敏结果仍包含 %q: %s", tc.name, secret, got)
+			}
+		}
+	}
+}
+
+// TestRedactForm 查询字符串和表单按字段脱敏，保留原始顺序和编码
+func TestRedactForm(t *testing.T) {
+	rc := newRequestCapture(config.RequestCaptureConfig{Query: true, RedactKeys: []string{"token", "pass word"}})
+	in := "page=1&TOKEN=abc&pass%20word=x&q=a%26b&flag"
+	want := "page=1&TOKEN=***&pass%20word=***&q=a%26b&flag"
+	if got := rc.redactForm(in); got != want {
+		t.Errorf("redactForm = %s，期望 %s", got, want)
+	}
+}
+
+// TestTrimPartialRune 截断时不留下不完整的多字节字符
+func TestTrimPartialRune(t *testing.T) {
+	s := "中文"
+	cases := map[int]string{6: "中文", 5: "中", 4: "中", 3: "中", 2: "", 0: ""}
+	for n, want := range cases {
+		if got := string(trimPartialRune([]byte(s)[:n])); got != want {
+			t.Errorf("trimPartialRune(%d 字节) = %q，期望 %q", n, got, want)
+		}
+	}
+}
+
+// newCaptureRouter 在请求日志路由上增加一个回显请求体的路由，状态码和响应类型由查询参数指定
+func newCaptureRouter(cfg config.RequestCaptureConfig) (*gin.Engine, func(t *testing.T) map[string]interface{}) {
+	r, logs := newRequestLogRouter(WithCapture(cfg))
+	r.POST("/echo", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		status, _ := strconv.Atoi(c.DefaultQuery("status", "200"))
+		c.Data(status, c.DefaultQuery("type", c.ContentType()), body)
+	})
+	fields := func(t *testing.T) map[string]interface{} {
+		t.Helper()
+		entries := requestLogs(logs)
+		if len(entries) != 1 {
+			t.Fatalf("应记录 1 条日志，实际 %d", len(entries))
+		}
+		return entries[0].ContextMap()
+	}
+	return r, fields
+}
+
+// postEcho 向 /echo 发送请求体
+func postEcho(r http.Handler, query, contentType, body string) {
+	req := httptest.NewRequest(http.MethodPost, "/echo?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	serve(r, req)
+}
+
+// TestRequestCaptureBodies 记录脱敏后的请求/响应体和查询字符串
+func TestRequestCaptureBodies(t *testing.T) {
+	r, fields := newCaptureRouter(config.RequestCaptureConfig{Query: true, RequestBody: true, ResponseBody: true, Sizes: true})
+
+	postEcho(r, "token=abc&page=1", "application/json", `{"user":{"password":"p"},"name":"a"}`)
+	got := fields(t)
+	want := `{"name":"a","user":{"password":"***"}}`
+	if got["http.request.body"] != want || got["http.response.body"] != want {
+		t.Errorf("请求/响应体应按结构脱敏，实际 %v", got)
+	}
+	if got["url.query"] != "token=***&page=1" {
+		t.Errorf("查询字符串应脱敏，实际 %v", got["url.query"])
+	}
+	if got["http.request.body.size"] != int64(36) {
+		t.Errorf("请求体大小 = %v，期望 36", got["http.request.body.size"])
+	}
+
+	r, fields = newCaptureRouter(config.RequestCaptureConfig{RequestBody: true, ContentTypes: []string{"application/x-www-form-urlencoded"}})
+	postEcho(r, "", "application/x-www-form-urlencoded", "name=a&password=p")
+	if got := fields(t); got["http.request.body"] != "name=a&password=***" {
+		t.Errorf("表单请求体应脱敏，实际 %v", got["http.request.body"])
+	}
+}
+
+// TestRequestCaptureTruncation 超过 MaxBodyBytes 的请求体被截断，不在多字节字符中间截断，并标记截掉的字节数
+func TestRequestCaptureTruncation(t *testing.T) {
+	r, fields := newCaptureRouter(config.RequestCaptureConfig{RequestBody: true, MaxBodyBytes: 5, ContentTypes: []string{"text/plain"}})
+
+	postEcho(r, "", "text/plain; charset=utf-8", "中文字")
+	if got := fields(t)["http.request.body"]; got != "中...[truncated 6 bytes]" {
+		t.Errorf("截断后的请求体 = %v", got)
+	}
+
+	r, fields = newCaptureRouter(config.RequestCaptureConfig{RequestBody: true, MaxBodyBytes: 24})
+	postEcho(r, "", "application/json", `{"name":"a","password":"secret-value"}`)
+	if got := fields(t)["http.request.body"]; got != `{"name":"a","password":"***"...[truncated 14 bytes]` {
+		t.Errorf("截断的 JSON 应使用正则脱敏，实际 %v", got)
+	}
+}
+
+// TestRequestCaptureContentTypes 只记录 ContentTypes 中的媒体类型，支持 "text/*" 通配
+func TestRequestCaptureContentTypes(t *testing.T) {
+	r, fields := newCaptureRouter(config.RequestCaptureConfig{RequestBody: true, ResponseBody: true})
+	postEcho(r, "type=application/json", "text/plain", "hello")
+	got := fields(t)
+	if _, ok := got["http.request.body"]; ok {
+		t.Errorf("默认只记录 application/json 请求体，实际 %v", got)
+	}
+	if got["http.response.body"] != "hello" {
+		t.Errorf("响应体按响应的 Content-Type 判断，应记录，实际 %v", got)
+	}
+
+	r, fields = newCaptureRouter(config.RequestCaptureConfig{RequestBody: true, ResponseBody: true, ContentTypes: []string{"text/*"}})
+	postEcho(r, "type=application/octet-stream", "text/plain", "hello")
+	got = fields(t)
+	if got["http.request.body"] != "hello" {
+		t.Errorf("text/* 应匹配 text/plain，实际 %v", got)
+	}
+	if _, ok := got["http.response.body"]; ok {
+		t.Errorf("不在范围内的响应类型不应记录，实际 %v", got)
+	}
+}
+
+// TestRequestCaptureStatusClasses 配置 StatusClasses 时只记录对应状态码的请求/响应体，其他字段不受影响
+func TestRequestCaptureStatusClasses(t *testing.T) {
+	r, fields := newCaptureRouter(config.RequestCaptureConfig{RequestBody: true, ResponseBody: true, Query: true, StatusClasses: []string{"4xx", "5xx"}})
+
+	postEcho(r, "status=200", "application/json", `{"id":1}`)
+	got := fields(t)
+	if _, ok := got["http.request.body"]; ok {
+		t.Errorf("2xx 请求不应记录请求体，实际 %v", got)
+	}
+	if got["url.query"] != "status=200" {
+		t.Errorf("查询字符串不受状态码范围限制，实际 %v", got)
+	}
+
+	for _, status := range []string{"400", "503"} {
+		postEcho(r, "status="+status, "application/json", `{"id":1}`)
+		if got := fields(t); got["http.request.body"] != `{"id":1}` || got["http.response.body"] != `{"id":1}` {
+			t.Errorf("%s 请求应记录请求/响应体，实际 %v", status, got)
+		}
+	}
+}
@@ -1,24 +1,102 @@
 package middleware
 
 import (
+	"github.com/Xushengqwer/go-common/config"
 	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/core"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/trace" // 导入 OTel trace
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// skipRequestLogKey 是 SkipRequestLog 在 Gin Context 中设置的跳过标志
+const skipRequestLogKey = "skipRequestLog"
+
+// SkipRequestLog 返回路由级中间件，为单个路由 (或路由组) 关闭请求日志，例如:
+//
+//	router.GET("/healthz", middleware.SkipRequestLog(), healthHandler)
+//
+// 与 RequestLogConfig.SkipPaths 相同，5xx 响应仍然会被记录。
+func SkipRequestLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(skipRequestLogKey, true)
+		c.Next()
+	}
+}
+
+// RequestLoggerOption 配置 RequestLoggerMiddleware
+type RequestLoggerOption func(*requestLoggerOptions)
+
+type requestLoggerOptions struct {
+	config.RequestLogConfig
+}
+
+// WithCapture 开启额外的采集项: 请求/响应大小、查询字符串、路由模板以及 (脱敏后的) 请求/响应体
+// 请求/响应体可以按路由 (cfg.Routes) 和状态码类别 (cfg.StatusClasses，例如只记录 4xx/5xx) 限定范围。
+func WithCapture(cfg config.RequestCaptureConfig) RequestLoggerOption {
+	return func(o *requestLoggerOptions) {
+		o.Capture = cfg
+	}
+}
+
+// WithRequestLogConfig 使用完整的 config.RequestLogConfig (跳过路径、采样率、慢请求阈值以及 Capture)，
+// 与 WithCapture 同时使用时以后传入的为准。
+// cfg 应通过 core.LoadConfig 加载 (或预先调用 cfg.Validate)，无效的 SkipPathRegexps 会在加载时返回错误。
+func WithRequestLogConfig(cfg config.RequestLogConfig) RequestLoggerOption {
+	return func(o *requestLoggerOptions) {
+		o.RequestLogConfig = cfg
+	}
+}
+
 // RequestLoggerMiddleware (已改造) - 记录请求摘要日志，包含 OTel TraceID 和 SpanID
 // logger 参数仍然需要，用于实际记录日志
 // 移除了 isGateway 参数和逻辑，使其更通用
-func RequestLoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
+// opts 可选配置 (WithCapture、WithRequestLogConfig):
+// - 日志级别按状态码选择: 2xx/3xx 为 Info，4xx 为 Warn，5xx 为 Error；超过 SlowThreshold 的请求至少为 Warn
+// - SkipPaths / SkipPathRegexps 和路由级的 SkipRequestLog 可以关闭健康检查、静态资源等请求的日志 (5xx 仍会记录)
+// - SuccessSampleRate 对 2xx/3xx 请求采样，4xx/5xx 和慢请求始终记录
+// - Capture 可选记录请求/响应大小、查询字符串、路由模板以及 (脱敏后的) 请求/响应体
+//
+// 未经校验的配置中无法编译的正则会被忽略并记录一条 Error 日志，不会 panic。
+func RequestLoggerMiddleware(logger *core.ZapLogger, opts ...RequestLoggerOption) gin.HandlerFunc {
+	// 按级别检查后再写入，需要直接使用底层的 *zap.Logger (抵消 ZapLogger 包装方法的 CallerSkip)
+	zl := logger.Logger().WithOptions(zap.AddCallerSkip(-1))
+	var o requestLoggerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	cfg := o.RequestLogConfig
+	skipPaths := newRoutePatterns(cfg.SkipPaths)
+	skipRegexps := make([]*regexp.Regexp, 0, len(cfg.SkipPathRegexps))
+	for _, expr := range cfg.SkipPathRegexps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			logger.Error("RequestLoggerMiddleware: 忽略无效的 skipPathRegexps 正则", zap.String("regexp", expr), zap.Error(err))
+			continue
+		}
+		skipRegexps = append(skipRegexps, re)
+	}
+	capture := newRequestCapture(cfg.Capture)
+
 	return func(c *gin.Context) {
 		// 1. 记录请求开始时间
 		startTime := time.Now()
+		skipped := requestLogSkipped(c, skipPaths, skipRegexps)
+		writer := c.Writer // 采集响应体时会替换 Writer，处理结束后还原
+		var captured func() []zap.Field
+		if capture != nil && !skipped {
+			captured = capture.start(c)
+		}
 
 		// 2. 处理后续请求
 		c.Next() // 执行后续中间件和 Handler
+		c.Writer = writer
 
 		// 3. 计算总处理时长
 		endTime := time.Now()
@@ -27,7 +105,23 @@ func RequestLoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		// 4. 从上下文中获取请求信息
 		method := c.Request.Method
 		path := c.Request.URL.Path
-		statusCode := c.Writer.Status()
+		statusCode := ResponseStatus(c)
+
+		// 选择日志级别，跳过或未被采样的请求直接返回 (此时才计算剩余字段)
+		slow := cfg.SlowThreshold > 0 && totalLatency >= cfg.SlowThreshold
+		level := requestLogLevel(statusCode, slow)
+		if statusCode < http.StatusInternalServerError {
+			if skipped || c.GetBool(skipRequestLogKey) {
+				return
+			}
+			if level == zapcore.InfoLevel && cfg.SuccessSampleRate > 0 && cfg.SuccessSampleRate < 1 && rand.Float64() >= cfg.SuccessSampleRate {
+				return
+			}
+		}
+		ce := zl.Check(level, "HTTP request processed")
+		if ce == nil {
+			return
+		}
 		clientIP := c.ClientIP()
 		userAgent := c.Request.UserAgent()
 
@@ -67,10 +161,46 @@ func RequestLoggerMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			zap.Duration("duration", totalLatency),       // 总请求处理时长 (ms 或 s)
 			zap.String("client.address", clientIP),       // 客户端 IP (遵循 OTel 语义约定更好)
 			zap.String("user_agent.original", userAgent), // 用户代理
-			// 可以添加其他有用的字段，如 request body size, response body size 等
 		}
+		if slow {
+			logFields = append(logFields, zap.Bool("slow", true))
+		}
+		if captured != nil {
+			logFields = append(logFields, captured()...) // 路由模板、查询字符串、大小和请求/响应体 (cfg.Capture)
+		}
+
+		// 7. 按选定的级别记录请求日志
+		ce.Write(logFields...)
+	}
+}
+
+// requestLogSkipped 判断请求路径是否匹配 SkipPaths 或 SkipPathRegexps
+func requestLogSkipped(c *gin.Context, paths []routePattern, regexps []*regexp.Regexp) bool {
+	fullPath, urlPath := c.FullPath(), c.Request.URL.Path
+	for _, p := range paths {
+		if (fullPath != "" && matchRoute(fullPath, p.path, p.prefix)) || matchRoute(urlPath, p.path, p.prefix) {
+			return true
+		}
+	}
+	for _, re := range regexps {
+		if re.MatchString(urlPath) {
+			return true
+		}
+	}
+	return false
+}
 
-		// 7. 记录请求日志 (通常是 INFO 级别)
-		logger.Info("HTTP request processed", logFields...)
+// requestLogLevel 按状态码选择日志级别，慢请求至少为 Warn
+func requestLogLevel(status int, slow bool) zapcore.Level {
+	level := zapcore.InfoLevel
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		level = zapcore.WarnLevel
+	}
+	if slow && level < zapcore.WarnLevel {
+		level = zapcore.WarnLevel
 	}
+	return level
 }
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newRequestLogRouter 返回挂载 RequestLoggerMiddleware 的路由和记录日志的 observer
+func newRequestLogRouter(opts ...RequestLoggerOption) (*gin.Engine, *observer.ObservedLogs) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	r := gin.New()
+	r.Use(RequestLoggerMiddleware(core.WrapZapLogger(zap.New(observed)), opts...))
+	r.GET("/ok", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/bad", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/static/app.js", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/static/broken.js", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	return r, logs
+}
+
+// requestLogs 取出 (并清空) observer 中的请求日志
+func requestLogs(logs *observer.ObservedLogs) []observer.LoggedEntry {
+	var entries []observer.LoggedEntry
+	for _, e := range logs.TakeAll() {
+		if e.Message == "HTTP request processed" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// TestRequestLoggerDefaults 不传选项时记录所有请求，级别按状态码选择
+func TestRequestLoggerDefaults(t *testing.T) {
+	r, logs := newRequestLogRouter()
+	cases := map[string]zapcore.Level{
+		"/ok":   zapcore.InfoLevel,
+		"/bad":  zapcore.WarnLevel,
+		"/fail": zapcore.ErrorLevel,
+	}
+	for path, want := range cases {
+		serve(r, httptest.NewRequest(http.MethodGet, path, nil))
+		entries := requestLogs(logs)
+		if len(entries) != 1 {
+			t.Fatalf("%s: 应记录 1 条日志，实际 %d", path, len(entries))
+		}
+		if entries[0].Level != want {
+			t.Errorf("%s: 日志级别应为 %v，实际 %v", path, want, entries[0].Level)
+		}
+	}
+}
+
+// TestRequestLoggerWithCapture WithCapture 采集的字段写入请求日志
+func TestRequestLoggerWithCapture(t *testing.T) {
+	r, logs := newRequestLogRouter(WithCapture(config.RequestCaptureConfig{Route: true, Sizes: true}))
+	serve(r, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	entries := requestLogs(logs)
+	if len(entries) != 1 {
+		t.Fatalf("应记录 1 条日志，实际 %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["http.route"] != "/ok" {
+		t.Errorf("应记录路由模板，实际字段 %v", fields)
+	}
+}
+
+// TestRequestLoggerSkipPathRegexps 命中 SkipPathRegexps 的请求不记录，5xx 仍然记录
+func TestRequestLoggerSkipPathRegexps(t *testing.T) {
+	r, logs := newRequestLogRouter(WithRequestLogConfig(config.RequestLogConfig{
+		SkipPathRegexps: []string{`\.js$`},
+	}))
+
+	serve(r, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	if n := len(requestLogs(logs)); n != 0 {
+		t.Errorf("命中 skipPathRegexps 的 2xx 请求不应记录，实际 %d 条", n)
+	}
+	serve(r, httptest.NewRequest(http.MethodGet, "/static/broken.js", nil))
+	if n := len(requestLogs(logs)); n != 1 {
+		t.Errorf("5xx 请求应始终记录，实际 %d 条", n)
+	}
+}
+
+// TestRequestLoggerInvalidRegexpDoesNotPanic 未校验的配置中的无效正则被忽略并记录错误，不会 panic
+func TestRequestLoggerInvalidRegexpDoesNotPanic(t *testing.T) {
+	cfg := config.RequestLogConfig{SkipPathRegexps: []string{`(`, `\.js$`}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate 应对无效正则返回错误")
+	}
+
+	r, logs := newRequestLogRouter(WithRequestLogConfig(cfg))
+	if n := logs.FilterMessage("RequestLoggerMiddleware: 忽略无效的 skipPathRegexps 正则").Len(); n != 1 {
+		t.Errorf("应记录 1 条无效正则的错误日志，实际 %d", n)
+	}
+	serve(r, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	if n := len(requestLogs(logs)); n != 0 {
+		t.Errorf("其余有效的正则应继续生效，实际记录 %d 条", n)
+	}
+}
+
+// TestRequestLoggerSuccessSampleRate 只对 2xx/3xx 请求采样，4xx/5xx 始终记录
+func TestRequestLoggerSuccessSampleRate(t *testing.T) {
+	r, logs := newRequestLogRouter(WithRequestLogConfig(config.RequestLogConfig{SuccessSampleRate: 0.5}))
+
+	const n = 400
+	for i := 0; i < n; i++ {
+		serve(r, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	// 期望 200 条，标准差 10，取足够宽的范围避免偶发失败
+	if got := len(requestLogs(logs)); got < 140 || got > 260 {
+		t.Errorf("采样率 0.5 时 %d 个成功请求应记录约一半，实际 %d 条", n, got)
+	}
+
+	r, logs = newRequestLogRouter(WithRequestLogConfig(config.RequestLogConfig{SuccessSampleRate: 0.000001}))
+	for _, path := range []string{"/bad", "/fail"} {
+		for i := 0; i < 10; i++ {
+			serve(r, httptest.NewRequest(http.MethodGet, path, nil))
+		}
+		if got := len(requestLogs(logs)); got != 10 {
+			t.Errorf("%s: 错误请求不参与采样，应记录 10 条，实际 %d", path, got)
+		}
+	}
+}
+
+// TestRequestLoggerSlowThreshold 超过 SlowThreshold 的请求提升为 Warn 并标记 slow=true，且不参与采样
+func TestRequestLoggerSlowThreshold(t *testing.T) {
+	r, logs := newRequestLogRouter(WithRequestLogConfig(config.RequestLogConfig{
+		SlowThreshold:     10 * time.Millisecond,
+		SuccessSampleRate: 0.000001,
+	}))
+	r.GET("/slow", func(c *gin.Context) {
+		time.Sleep(20 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	serve(r, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	entries := requestLogs(logs)
+	if len(entries) != 1 {
+		t.Fatalf("慢请求应始终记录，实际 %d 条", len(entries))
+	}
+	if entries[0].Level != zapcore.WarnLevel || entries[0].ContextMap()["slow"] != true {
+		t.Errorf("慢请求应为 Warn 且 slow=true，实际 %v %v", entries[0].Level, entries[0].ContextMap())
+	}
+
+	r, logs = newRequestLogRouter(WithRequestLogConfig(config.RequestLogConfig{SlowThreshold: time.Hour}))
+	serve(r, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	entries = requestLogs(logs)
+	if len(entries) != 1 || entries[0].Level != zapcore.InfoLevel {
+		t.Fatalf("未超过阈值的请求应为 Info，实际 %v", entries)
+	}
+	if _, ok := entries[0].ContextMap()["slow"]; ok {
+		t.Error("未超过阈值的请求不应带 slow 字段")
+	}
+}
+
+// TestSkipRequestLog 路由级的 SkipRequestLog 关闭该路由的请求日志，5xx 仍然记录
+func TestSkipRequestLog(t *testing.T) {
+	r, logs := newRequestLogRouter()
+	r.GET("/healthz", SkipRequestLog(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/healthz/db", SkipRequestLog(), func(c *gin.Context) { c.Status(http.StatusServiceUnavailable) })
+	r.GET("/healthz/bad", SkipRequestLog(), func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+
+	for _, path := range []string{"/healthz", "/healthz/bad"} {
+		serve(r, httptest.NewRequest(http.MethodGet, path, nil))
+		if n := len(requestLogs(logs)); n != 0 {
+			t.Errorf("%s: SkipRequestLog 的路由不应记录，实际 %d 条", path, n)
+		}
+	}
+	serve(r, httptest.NewRequest(http.MethodGet, "/healthz/db", nil))
+	if n := len(requestLogs(logs)); n != 1 {
+		t.Errorf("SkipRequestLog 的路由返回 5xx 时应记录，实际 %d 条", n)
+	}
+	serve(r, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if n := len(requestLogs(logs)); n != 1 {
+		t.Errorf("其他路由不受影响，实际 %d 条", n)
+	}
+}
@@ -0,0 +1,325 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+	"github.com/Xushengqwer/go-common/core/deadline"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestLogger 返回只输出 fatal 日志的 ZapLogger，避免测试输出被中间件日志淹没
+func newTestLogger(t *testing.T) *core.ZapLogger {
+	t.Helper()
+	logger, err := core.NewZapLogger(config.ZapConfig{Level: "fatal"})
+	if err != nil {
+		t.Fatalf("NewZapLogger: %v", err)
+	}
+	return logger
+}
+
+// serve 执行一次请求并返回响应
+func serve(r http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// assertTimeoutResponse 检查响应是标准的 504 超时响应
+func assertTimeoutResponse(t *testing.T, w *httptest.ResponseRecorder) {
+	t.Helper()
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("状态码 = %d，期望 504", w.Code)
+	}
+	if got := w.Body.String(); got != string(timeoutResponseBody) {
+		t.Errorf("响应体 = %q，期望只有超时响应", got)
+	}
+}
+
+func TestRequestTimeoutFastHandler(t *testing.T) {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Header("X-Trace-Id", "trace-1") // 上游中间件设置的响应头应被保留
+		c.Next()
+	})
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), time.Second))
+	r.GET("/", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); !ok {
+			t.Error("处理程序的 context 应带有截止时间")
+		}
+		c.Header("X-Handler", "1")
+		c.String(http.StatusCreated, "hello")
+	})
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "hello" {
+		t.Errorf("响应 = %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Trace-Id") != "trace-1" || w.Header().Get("X-Handler") != "1" {
+		t.Errorf("响应头 = %v", w.Header())
+	}
+}
+
+func TestRequestTimeoutSlowHandler(t *testing.T) {
+	writeErr := make(chan error, 1)
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), 20*time.Millisecond))
+	r.GET("/", func(c *gin.Context) {
+		c.Header("X-Handler", "1")
+		_, _ = c.Writer.WriteString("partial") // 超时前缓冲的内容应被丢弃
+		<-c.Request.Context().Done()
+		c.String(http.StatusOK, "too late")
+		_, err := c.Writer.WriteString("more")
+		writeErr <- err
+	})
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	assertTimeoutResponse(t, w)
+	if w.Header().Get("X-Handler") != "" {
+		t.Error("超时响应不应带有处理程序设置的响应头")
+	}
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("超时后写入应返回 http.ErrHandlerTimeout，实际 %v", err)
+	}
+}
+
+func TestRequestTimeoutLateWritesAreDropped(t *testing.T) {
+	// 处理程序不检查 context，超时之后才写响应
+	release := make(chan struct{})
+	writeErr := make(chan error, 1)
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), 20*time.Millisecond))
+	r.GET("/", func(c *gin.Context) {
+		<-release
+		_, err := c.Writer.WriteString("late")
+		writeErr <- err
+		c.Writer.WriteHeader(http.StatusOK)
+		c.Writer.Flush()
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	go func() {
+		time.Sleep(60 * time.Millisecond)
+		close(release)
+	}()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body := new(strings.Builder)
+	_, _ = bufio.NewReader(resp.Body).WriteTo(body)
+	if resp.StatusCode != http.StatusGatewayTimeout || body.String() != string(timeoutResponseBody) {
+		t.Errorf("响应 = %d %q", resp.StatusCode, body.String())
+	}
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("超时后写入应返回 http.ErrHandlerTimeout，实际 %v", err)
+	}
+}
+
+func TestRequestTimeoutStreamingCutOff(t *testing.T) {
+	writeErr := make(chan error, 1)
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), 50*time.Millisecond))
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		_, _ = c.Writer.WriteString("data: 1\n\n")
+		c.Writer.Flush()
+		<-c.Request.Context().Done()
+		_, err := c.Writer.WriteString("data: 2\n\n")
+		writeErr <- err
+		c.Writer.Flush()
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatalf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body := new(strings.Builder)
+	_, _ = bufio.NewReader(resp.Body).WriteTo(body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("流式响应头已发出，状态码应保持 200，实际 %d", resp.StatusCode)
+	}
+	if body.String() != "data: 1\n\n" {
+		t.Errorf("超时后应中止后续写入，实际收到 %q", body.String())
+	}
+	if err := <-writeErr; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("超时后写入应返回 http.ErrHandlerTimeout，实际 %v", err)
+	}
+}
+
+func TestRequestTimeoutErrorMapping(t *testing.T) {
+	logger := newTestLogger(t)
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(logger, 20*time.Millisecond), ErrorMappingMiddleware(logger, nil))
+	r.GET("/missing", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("load post: %w", commonerrors.ErrRepoNotFound))
+	})
+	r.GET("/slow", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		_ = c.Error(c.Request.Context().Err())
+	})
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), commonerrors.ErrRepoNotFound.Error()) {
+		t.Errorf("超时前返回的错误应被映射，实际 %d %q", w.Code, w.Body.String())
+	}
+
+	// 超时后处理程序返回的 context 错误不应再写出第二个响应
+	assertTimeoutResponse(t, serve(r, httptest.NewRequest(http.MethodGet, "/slow", nil)))
+}
+
+func TestRequestTimeoutPanics(t *testing.T) {
+	logger := newTestLogger(t)
+	r := gin.New()
+	r.Use(ErrorHandlingMiddleware(logger), RequestTimeoutMiddleware(logger, 20*time.Millisecond))
+	r.GET("/panic", func(c *gin.Context) {
+		_, _ = c.Writer.WriteString("partial")
+		panic("boom")
+	})
+	r.GET("/panic-after-timeout", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		panic("boom")
+	})
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "partial") {
+		t.Errorf("panic 应交给 ErrorHandlingMiddleware 写出 500 并丢弃缓冲的内容，实际 %d %q", w.Code, w.Body.String())
+	}
+
+	assertTimeoutResponse(t, serve(r, httptest.NewRequest(http.MethodGet, "/panic-after-timeout", nil)))
+}
+
+func TestRequestTimeoutUpstreamDeadline(t *testing.T) {
+	called := false
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), time.Second))
+	r.GET("/", func(c *gin.Context) {
+		called = true
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(deadline.Header, deadline.Format(time.Now().Add(-time.Second)))
+	assertTimeoutResponse(t, serve(r, req))
+	if called {
+		t.Error("上游截止时间已过时不应执行处理程序")
+	}
+}
+
+func TestRequestTimeoutSkip(t *testing.T) {
+	r := gin.New()
+	r.Use(SkipTimeoutForPaths("/ws"), RequestTimeoutMiddleware(newTestLogger(t), 10*time.Millisecond))
+	r.GET("/ws", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("跳过超时的路由不应设置截止时间")
+		}
+		time.Sleep(20 * time.Millisecond)
+		c.String(http.StatusOK, "ok")
+	})
+
+	if w := serve(r, httptest.NewRequest(http.MethodGet, "/ws", nil)); w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("响应 = %d %q", w.Code, w.Body.String())
+	}
+}
+
+// TestRequestTimeoutConcurrentRace 让处理程序的完成时间与超时竞争，配合 go test -race 检查数据竞争，
+// 并确认每个响应要么是完整的处理程序响应，要么是完整的 504，不会混在一起
+func TestRequestTimeoutConcurrentRace(t *testing.T) {
+	r := gin.New()
+	r.Use(RequestTimeoutMiddleware(newTestLogger(t), 5*time.Millisecond))
+	r.GET("/", func(c *gin.Context) {
+		time.Sleep(time.Duration(c.Request.URL.Query().Get("n")[0]-'0') * time.Millisecond)
+		for i := 0; i < 10; i++ {
+			_, _ = c.Writer.WriteString("chunk")
+		}
+		c.Status(http.StatusOK)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := serve(r, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/?n=%d", i%10), nil))
+			switch {
+			case w.Code == http.StatusOK && w.Body.String() == strings.Repeat("chunk", 10):
+			case w.Code == http.StatusGatewayTimeout && w.Body.String() == string(timeoutResponseBody):
+			default:
+				t.Errorf("响应不完整: %d %q", w.Code, w.Body.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTimeoutWriterIgnoresWritesAfterFinish(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	tw := newTimeoutWriter(ctx, c.Writer, timeoutResponseBody, nil, nil)
+
+	_, _ = tw.WriteString("done")
+	if tw.finish(false) {
+		t.Fatal("未超时时 finish 应返回 false")
+	}
+	tw.timeout() // 中间件结束后的超时回调不应再写任何内容
+	if rec.Code != http.StatusOK || rec.Body.String() != "done" {
+		t.Errorf("响应 = %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestTimeoutClientClosed(t *testing.T) {
+	logger := newTestLogger(t)
+	observed := make(chan [2]int, 1)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		observed <- [2]int{ResponseStatus(c), c.Writer.Status()}
+	})
+	r.Use(RequestTimeoutMiddleware(logger, time.Minute), ErrorMappingMiddleware(logger, nil))
+	r.GET("/", func(c *gin.Context) {
+		_, _ = c.Writer.WriteString("partial")
+		<-c.Request.Context().Done()
+		_ = c.Error(c.Request.Context().Err())
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	w := serve(r, req)
+
+	got := <-observed
+	if got[0] != StatusClientClosedRequest {
+		t.Errorf("ResponseStatus = %d，期望 499", got[0])
+	}
+	if got[1] == StatusClientClosedRequest || w.Code == StatusClientClosedRequest {
+		t.Errorf("499 不应写到真正的 ResponseWriter 上: writer=%d, 响应=%d", got[1], w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("客户端断开后不应写出任何响应体，实际 %q", w.Body.String())
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"github.com/Xushengqwer/go-common/core"
+	"github.com/Xushengqwer/go-common/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SlowQueryHandler 返回一个 Gin 处理器，以标准 APIResponse 输出慢查询聚合器当前周期和上一周期的汇总
+// - aggregator 为 nil (未开启 SlowQueryAggregation) 时返回空汇总
+// 例如: adminGroup.GET("/debug/slow-queries", middleware.SlowQueryHandler(gormLogger.SlowQueryAggregator()))
+func SlowQueryHandler(aggregator *core.SlowQueryAggregator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if aggregator == nil {
+			response.RespondSuccess(c, gin.H{"current": nil, "previous": nil})
+			return
+		}
+		current := aggregator.Snapshot()
+		response.RespondSuccess(c, gin.H{
+			"current":  current,
+			"previous": aggregator.Previous(),
+		})
+	}
+}
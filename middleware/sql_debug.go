@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/core"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSQLDebugHeader 是未配置 SQLDebugConfig.Header 时使用的调试请求头
+const defaultSQLDebugHeader = "X-Debug-SQL"
+
+// SQLDebugMiddleware 为满足调试条件的请求在 context 中写入 GORM 日志级别覆盖值
+// 触发条件 (满足任一即可):
+// - 请求头 cfg.Header (默认 X-Debug-SQL) 的值与 cfg.Token 一致 (Token 为空时不接受请求头)
+// - 请求的角色 (UserContextMiddleware 写入的 Role) 属于 cfg.AdminRoles
+//
+// 覆盖值只作用于本次请求: 业务代码需要使用 db.WithContext(c.Request.Context()) 执行查询。
+// 依赖角色判断时，该中间件需要注册在 UserContextMiddleware 之后。
+func SQLDebugMiddleware(cfg config.SQLDebugConfig) gin.HandlerFunc {
+	header := cfg.Header
+	if header == "" {
+		header = defaultSQLDebugHeader
+	}
+	level := core.ParseGormLogLevel(cfg.Level)
+	adminRoles := make(map[string]struct{}, len(cfg.AdminRoles))
+	for _, r := range cfg.AdminRoles {
+		adminRoles[r] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if sqlDebugTokenValid(cfg.Token, c.GetHeader(header)) || hasAdminRole(c, adminRoles) {
+			c.Request = c.Request.WithContext(core.WithGormLogLevel(c.Request.Context(), level))
+		}
+		c.Next()
+	}
+}
+
+// sqlDebugTokenValid 使用常量时间比较校验调试令牌，未配置令牌时始终返回 false
+func sqlDebugTokenValid(token, got string) bool {
+	if token == "" || got == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(got)) == 1
+}
+
+// hasAdminRole 判断请求的角色是否在 adminRoles 中
+// 优先读取 Gin Context 中的 "Role"，其次读取 request context 中的 constants.RoleKey
+func hasAdminRole(c *gin.Context, adminRoles map[string]struct{}) bool {
+	if len(adminRoles) == 0 {
+		return false
+	}
+	role := c.GetString("Role")
+	if role == "" {
+		role, _ = c.Request.Context().Value(constants.RoleKey).(string)
+	}
+	_, ok := adminRoles[role]
+	return role != "" && ok
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+)
+
+// newSQLDebugRouter 返回挂载 SQLDebugMiddleware 的路由，处理程序用请求 context 执行一次查询
+// GormLogger 的全局级别为 warn，普通查询不会输出日志
+func newSQLDebugRouter(t *testing.T, cfg config.SQLDebugConfig) (*gin.Engine, *gorm.DB, *observer.ObservedLogs) {
+	t.Helper()
+	zc, logs := observer.New(zapcore.DebugLevel)
+	gormLogger := core.NewGormLogger(core.WrapZapLogger(zap.New(zc)), config.GormLogConfig{Level: "warn", SkipCallerLookup: true})
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormLogger})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		// 模拟 UserContextMiddleware 写入的角色
+		if role := c.GetHeader("X-Test-Role"); role != "" {
+			c.Set("Role", role)
+		}
+		c.Next()
+	})
+	r.Use(SQLDebugMiddleware(cfg))
+	r.GET("/", func(c *gin.Context) {
+		var n int
+		db.WithContext(c.Request.Context()).Raw("SELECT 1").Scan(&n)
+		c.Status(http.StatusOK)
+	})
+	return r, db, logs
+}
+
+// TestSQLDebugMiddleware 调试令牌或管理员角色只提升本次请求的 SQL 日志级别
+func TestSQLDebugMiddleware(t *testing.T) {
+	cfg := config.SQLDebugConfig{Token: "s3cret", AdminRoles: []string{"admin"}}
+	cases := []struct {
+		name    string
+		headers map[string]string
+		logged  bool
+	}{
+		{"无调试条件", nil, false},
+		{"正确的令牌", map[string]string{"X-Debug-SQL": "s3cret"}, true},
+		{"错误的令牌", map[string]string{"X-Debug-SQL": "guess"}, false},
+		{"管理员角色", map[string]string{"X-Test-Role": "admin"}, true},
+		{"普通用户角色", map[string]string{"X-Test-Role": "user"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, db, logs := newSQLDebugRouter(t, cfg)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			serve(r, req)
+
+			if got := logs.FilterMessage("SQL query executed").Len() > 0; got != tc.logged {
+				t.Errorf("本次请求的 SQL 日志: 期望 %v，实际 %v", tc.logged, got)
+			}
+
+			// 全局级别不受影响: 之后不带覆盖值的查询仍然不输出 Info 日志
+			logs.TakeAll()
+			var n int
+			db.Raw("SELECT 1").Scan(&n)
+			serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+			if logs.Len() != 0 {
+				t.Errorf("其他查询不应输出日志，实际 %d 条", logs.Len())
+			}
+		})
+	}
+}
+
+// TestSQLDebugMiddlewareEmptyToken 未配置令牌时请求头不能开启调试
+func TestSQLDebugMiddlewareEmptyToken(t *testing.T) {
+	r, _, logs := newSQLDebugRouter(t, config.SQLDebugConfig{})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Debug-SQL", "")
+	req.Header.Set("X-Test-Role", "admin")
+	serve(r, req)
+	if logs.Len() != 0 {
+		t.Errorf("未配置令牌和管理员角色时不应开启调试，实际 %d 条日志", logs.Len())
+	}
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/core"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// defaultClientTimeoutHeader 是客户端指定超时时默认使用的请求头
+const defaultClientTimeoutHeader = "X-Request-Timeout"
+
+// noTimeout 是规则中表示不设置超时的取值
+const noTimeout = "none"
+
+// timeoutRule 是预处理后的 config.TimeoutRuleConfig
+type timeoutRule struct {
+	path    string
+	prefix  bool
+	methods map[string]struct{}
+	timeout time.Duration // <= 0 表示不设置超时
+	extend  bool          // 是否允许客户端把超时延长到 TimeoutPolicy.max
+}
+
+// TimeoutPolicy 根据路由模式和 HTTP 方法决定每个请求的超时时间
+// - 规则按顺序匹配 c.FullPath() (路由模式，未匹配到路由时为 URL 路径)，第一条命中的规则生效
+// - 客户端可以通过请求头 (默认 X-Request-Timeout) 缩短超时；只有开启 AllowClientExtend 的规则允许延长，且不超过 Max
+// - 请求头来自不受信任的客户端，默认不能借此让请求占用资源超过路由自身的超时
+type TimeoutPolicy struct {
+	defaultTimeout time.Duration
+	max            time.Duration
+	clientHeader   string
+	rules          []timeoutRule
+}
+
+// NewTimeoutPolicy 根据服务配置创建超时策略，cfg.TimeoutPolicy.Default 未配置时使用 cfg.RequestTimeout
+func NewTimeoutPolicy(cfg config.ServerConfig) (*TimeoutPolicy, error) {
+	pc := cfg.TimeoutPolicy
+	p := &TimeoutPolicy{
+		defaultTimeout: pc.Default,
+		max:            pc.Max,
+		clientHeader:   pc.ClientHeader,
+	}
+	if p.defaultTimeout <= 0 {
+		p.defaultTimeout = cfg.RequestTimeout
+	}
+	if p.clientHeader == "" {
+		p.clientHeader = defaultClientTimeoutHeader
+	}
+
+	for i, r := range pc.Rules {
+		if r.Path == "" {
+			return nil, fmt.Errorf("第 %d 条超时规则缺少 path", i)
+		}
+		tr := timeoutRule{path: r.Path, extend: r.AllowClientExtend}
+		if strings.HasSuffix(r.Path, "*") {
+			tr.path = strings.TrimSuffix(r.Path, "*")
+			tr.prefix = true
+		}
+		if len(r.Methods) > 0 {
+			tr.methods = make(map[string]struct{}, len(r.Methods))
+			for _, m := range r.Methods {
+				tr.methods[strings.ToUpper(m)] = struct{}{}
+			}
+		}
+		if !strings.EqualFold(r.Timeout, noTimeout) {
+			d, err := time.ParseDuration(r.Timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("第 %d 条超时规则 (%s) 的 timeout 无效: %q", i, r.Path, r.Timeout)
+			}
+			tr.timeout = d
+		}
+		p.rules = append(p.rules, tr)
+	}
+	return p, nil
+}
+
+// Resolve 返回请求应使用的超时时间，第二个返回值为 false 表示不设置超时
+func (p *TimeoutPolicy) Resolve(c *gin.Context) (time.Duration, bool) {
+	timeout, extend := p.routeTimeout(c)
+
+	if client, ok := parseClientTimeout(c.GetHeader(p.clientHeader)); ok {
+		// 默认以路由自身的超时为上限，开启延长且配置了 Max 时以 Max 为上限；
+		// 路由不设置超时时，客户端指定的任何超时都只会缩短 (仍不超过 Max)
+		limit := timeout
+		if (extend && p.max > 0) || timeout <= 0 {
+			limit = p.max
+		}
+		if limit > 0 && client > limit {
+			client = limit
+		}
+		timeout = client
+	}
+	return timeout, timeout > 0
+}
+
+// routeTimeout 返回路由规则 (或默认值) 对应的超时 (<= 0 表示不设置超时)，以及是否允许客户端延长
+func (p *TimeoutPolicy) routeTimeout(c *gin.Context) (time.Duration, bool) {
+	path := routePath(c)
+	method := c.Request.Method
+	for i := range p.rules {
+		r := &p.rules[i]
+		if !matchRoute(path, r.path, r.prefix) {
+			continue
+		}
+		if r.methods != nil {
+			if _, ok := r.methods[method]; !ok {
+				continue
+			}
+		}
+		return r.timeout, r.extend
+	}
+	return p.defaultTimeout, false
+}
+
+// parseClientTimeout 解析客户端请求头中的超时: Go duration 格式 ("1500ms"、"2s") 或毫秒数
+func parseClientTimeout(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(ms) * time.Millisecond, ms > 0
+	}
+	d, err := time.ParseDuration(value)
+	return d, err == nil && d > 0
+}
+
+// routePath 返回用于匹配的路径: 优先使用 Gin 路由模式，未匹配到路由时使用 URL 路径
+func routePath(c *gin.Context) string {
+	if p := c.FullPath(); p != "" {
+		return p
+	}
+	return c.Request.URL.Path
+}
+
+// matchRoute 判断路径是否匹配 (精确匹配或前缀匹配)
+func matchRoute(path, pattern string, prefix bool) bool {
+	if prefix {
+		return strings.HasPrefix(path, pattern)
+	}
+	return path == pattern
+}
+
+// TimeoutPolicyMiddleware 按 TimeoutPolicy 为每个请求设置超时，行为与 RequestTimeoutMiddleware 相同
+// 策略结果为不设置超时 (规则 "none") 或 Gin Context 中设置了跳过标志 (SkipTimeoutForPaths) 时直接执行后续处理。
+func TimeoutPolicyMiddleware(logger *core.ZapLogger, policy *TimeoutPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if skip, _ := c.Get(skipTimeoutKey); skip == true {
+			logger.Debug("跳过请求超时处理", zap.String("path", c.Request.URL.Path))
+			c.Next()
+			return
+		}
+		timeout, ok := policy.Resolve(c)
+		if !ok {
+			c.Next()
+			return
+		}
+		runWithTimeout(c, logger, timeout)
+	}
+}
+
+// SkipTimeoutForPaths 为匹配的路径设置跳过超时的标志，需要注册在 RequestTimeoutMiddleware / TimeoutPolicyMiddleware 之前
+// paths 为 Gin 路由模式或 URL 路径，以 "*" 结尾表示前缀匹配 (e.g., "/api/v1/events/*")
+func SkipTimeoutForPaths(paths ...string) gin.HandlerFunc {
+	patterns := newRoutePatterns(paths)
+
+	return func(c *gin.Context) {
+		fullPath, urlPath := c.FullPath(), c.Request.URL.Path
+		for _, p := range patterns {
+			if (fullPath != "" && matchRoute(fullPath, p.path, p.prefix)) || matchRoute(urlPath, p.path, p.prefix) {
+				c.Set(skipTimeoutKey, true)
+				break
+			}
+		}
+		c.Next()
+	}
+}
+
+// WithTimeout 返回为单个路由 (或路由组) 设置超时的中间件，例如:
+//
+//	router.POST("/posts/:id/publish", middleware.WithTimeout(logger, 3*time.Second), handler)
+//
+// 路由级中间件在全局超时中间件之后执行，context 的截止时间只能缩短不能延长，因此实际超时取两者中较短的一个；
+// 需要比全局默认值更长 (或不设置) 超时的路由，应在 TimeoutPolicy 中配置规则或使用 SkipTimeoutForPaths。
+func WithTimeout(logger *core.ZapLogger, timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		runWithTimeout(c, logger, timeout)
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Xushengqwer/go-common/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resolve 以 method/path 和可选的客户端超时请求头执行 Resolve
+func resolve(t *testing.T, p *TimeoutPolicy, method, path, clientTimeout string) (time.Duration, bool) {
+	t.Helper()
+	var timeout time.Duration
+	var ok bool
+	r := gin.New()
+	r.Handle(method, path, func(c *gin.Context) {
+		timeout, ok = p.Resolve(c)
+	})
+	req := httptest.NewRequest(method, path, nil)
+	if clientTimeout != "" {
+		req.Header.Set(defaultClientTimeoutHeader, clientTimeout)
+	}
+	serve(r, req)
+	return timeout, ok
+}
+
+func TestTimeoutPolicyResolve(t *testing.T) {
+	p, err := NewTimeoutPolicy(config.ServerConfig{
+		RequestTimeout: 5 * time.Second,
+		TimeoutPolicy: config.TimeoutPolicyConfig{
+			Max: 60 * time.Second,
+			Rules: []config.TimeoutRuleConfig{
+				{Path: "/upload/*", Timeout: "none"},
+				{Path: "/reports", Methods: []string{"post"}, Timeout: "30s", AllowClientExtend: true},
+				{Path: "/search", Timeout: "2s"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTimeoutPolicy: %v", err)
+	}
+
+	cases := []struct {
+		name, method, path, client string
+		want                       time.Duration
+		ok                         bool
+	}{
+		{"默认超时", http.MethodGet, "/posts", "", 5 * time.Second, true},
+		{"路由规则", http.MethodGet, "/search", "", 2 * time.Second, true},
+		{"方法不匹配时使用默认值", http.MethodGet, "/reports", "", 5 * time.Second, true},
+		{"不设置超时", http.MethodPost, "/upload/file", "", 0, false},
+		{"客户端缩短", http.MethodGet, "/posts", "1500ms", 1500 * time.Millisecond, true},
+		{"客户端使用毫秒数", http.MethodGet, "/posts", "800", 800 * time.Millisecond, true},
+		{"客户端不能延长默认超时", http.MethodGet, "/posts", "60s", 5 * time.Second, true},
+		{"客户端不能延长未开启的路由", http.MethodGet, "/search", "10s", 2 * time.Second, true},
+		{"开启的路由允许延长", http.MethodPost, "/reports", "45s", 45 * time.Second, true},
+		{"延长不超过 Max", http.MethodPost, "/reports", "10m", 60 * time.Second, true},
+		{"不设置超时的路由可以缩短", http.MethodPost, "/upload/file", "90s", 60 * time.Second, true},
+		{"无效的请求头被忽略", http.MethodGet, "/posts", "soon", 5 * time.Second, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := resolve(t, p, tc.method, tc.path, tc.client)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("Resolve = %v, %v，期望 %v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestTimeoutPolicyExtendWithoutMax(t *testing.T) {
+	p, err := NewTimeoutPolicy(config.ServerConfig{
+		RequestTimeout: 5 * time.Second,
+		TimeoutPolicy: config.TimeoutPolicyConfig{
+			Rules: []config.TimeoutRuleConfig{{Path: "/reports", Timeout: "30s", AllowClientExtend: true}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTimeoutPolicy: %v", err)
+	}
+	if got, _ := resolve(t, p, http.MethodGet, "/reports", "10m"); got != 30*time.Second {
+		t.Errorf("未配置 Max 时不允许延长，实际 %v", got)
+	}
+}
+
+func TestNewTimeoutPolicyInvalidRules(t *testing.T) {
+	for _, rule := range []config.TimeoutRuleConfig{
+		{Timeout: "1s"},
+		{Path: "/x", Timeout: "soon"},
+		{Path: "/x", Timeout: "-1s"},
+	} {
+		cfg := config.ServerConfig{TimeoutPolicy: config.TimeoutPolicyConfig{Rules: []config.TimeoutRuleConfig{rule}}}
+		if _, err := NewTimeoutPolicy(cfg); err == nil {
+			t.Errorf("规则 %+v 应返回错误", rule)
+		}
+	}
+}
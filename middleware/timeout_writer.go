@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errTimeoutHijack 表示在超时中间件内尝试 Hijack 连接
+var errTimeoutHijack = errors.New("RequestTimeoutMiddleware 不支持 Hijack，请为 WebSocket 等长连接路由跳过超时处理")
+
+var _ gin.ResponseWriter = (*timeoutWriter)(nil)
+
+// timeoutWriter 是 RequestTimeoutMiddleware 替换给下游处理程序的缓冲 ResponseWriter
+// 处理程序的状态码、响应头和响应体先写入缓冲区，只有以下两种情况会写到真正的连接上 (都在 mu 保护下进行):
+// - finish: 处理程序在超时前返回，缓冲区被整体刷出
+// - timeout: 超时时丢弃缓冲区并原子地写出 504，之后处理程序的写入都会被忽略 (返回 http.ErrHandlerTimeout)
+//
+// 处理程序调用 Flush (例如 c.Stream / SSE) 后进入直通模式: 已缓冲的内容立即发送，之后的写入直接写到连接上，
+// 此时响应头已经发出，超时只能中止后续写入，无法再改成 504。
+//
+// 处理程序在截止时间之后、超时回调获得锁之前的写入和返回同样按超时处理，保证截止时间之后不会再发出处理程序的响应。
+//
+// 客户端断开 (context 被取消而不是超时) 时丢弃缓冲区，之后的写入同样被忽略，不会再向已断开的连接写任何内容；
+// 原始 writer 保持不变，由中间件在请求 goroutine 上标记 clientClosedKey，外层通过 ResponseStatus 读到 499。
+type timeoutWriter struct {
+	gin.ResponseWriter // 原始 writer，只在持有 mu 时访问
+
+	ctx       context.Context  // 带截止时间的请求 context
+	body      []byte           // 超时时写出的响应体
+	onTimeout func(wrote bool) // 超时被处理时调用 (持有 mu)，wrote 表示是否写出了 504
+	onClosed  func()           // 客户端断开被处理时调用 (持有 mu)
+
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	size        int
+	wroteHeader bool // 处理程序是否已写入状态码 (或响应体)
+	committed   bool // 响应头是否已经发送到连接 (Flush 之后进入直通模式)
+	timedOut    bool // 是否已经超时，超时后忽略处理程序的所有写入
+	closed      bool // 客户端是否已经断开，断开后忽略处理程序的所有写入
+	abandoned   bool // 客户端在响应头发出之前断开 (需要记录 499)
+	finished    bool // 中间件是否已经结束，结束后超时回调不再做任何事
+}
+
+// newTimeoutWriter 包装原始 writer，响应头从原始 writer 复制一份 (保留上游中间件已经设置的头，例如 X-Trace-Id)
+func newTimeoutWriter(ctx context.Context, w gin.ResponseWriter, body []byte, onTimeout func(wrote bool), onClosed func()) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		body:           body,
+		onTimeout:      onTimeout,
+		onClosed:       onClosed,
+		header:         w.Header().Clone(),
+		status:         http.StatusOK,
+	}
+}
+
+// Header 返回处理程序使用的响应头，在刷出时才复制到真正的响应上
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader 记录状态码，重复调用或超时后调用会被忽略
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.closed || w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+// WriteHeaderNow 标记状态码已写入 (与 gin 的语义一致)
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut && !w.closed {
+		w.wroteHeader = true
+	}
+}
+
+// Write 把响应体写入缓冲区 (直通模式下直接写到连接)，超时后返回 http.ErrHandlerTimeout，客户端断开后返回 context.Canceled
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expiredLocked() {
+		return 0, w.writeErrLocked()
+	}
+	w.wroteHeader = true
+	var n int
+	var err error
+	if w.committed {
+		n, err = w.ResponseWriter.Write(data)
+	} else {
+		n, err = w.buf.Write(data)
+	}
+	w.size += n
+	return n, err
+}
+
+// WriteString 等同于 Write
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Status 返回处理程序写入的状态码
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Size 返回处理程序写入的响应体字节数，尚未写入状态码时返回 -1 (与 gin 的语义一致)
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		return -1
+	}
+	return w.size
+}
+
+// Written 判断处理程序是否已写入状态码或响应体
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// Flush 发送已缓冲的内容并切换到直通模式，用于流式响应
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expiredLocked() {
+		return
+	}
+	w.wroteHeader = true
+	w.commitLocked()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack 不受支持: 连接被接管后超时回调无法安全地写出 504
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errTimeoutHijack
+}
+
+// Pusher 不受支持，返回 nil
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
+
+// commitLocked 把响应头、状态码和缓冲的响应体写到真正的连接上，调用方必须持有 mu
+func (w *timeoutWriter) commitLocked() {
+	if w.committed {
+		return
+	}
+	dst := w.ResponseWriter.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	if !w.wroteHeader {
+		// 处理程序只设置了响应头而没有写入内容，交给 gin 在请求结束时写出默认状态码
+		return
+	}
+	w.committed = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// expiredLocked 判断是否已经超时或客户端已经断开；context 已结束但回调尚未执行时，立即按对应的情况处理，调用方必须持有 mu
+func (w *timeoutWriter) expiredLocked() bool {
+	if !w.timedOut && !w.closed {
+		switch err := w.ctx.Err(); {
+		case errors.Is(err, context.DeadlineExceeded):
+			w.timeoutLocked()
+		case errors.Is(err, context.Canceled):
+			w.closeLocked()
+		}
+	}
+	return w.timedOut || w.closed
+}
+
+// writeErrLocked 返回超时或客户端断开后写入时使用的错误，调用方必须持有 mu
+func (w *timeoutWriter) writeErrLocked() error {
+	if w.closed {
+		return context.Canceled
+	}
+	return http.ErrHandlerTimeout
+}
+
+// finish 在处理程序返回后调用: 未超时时刷出缓冲区；panicked 为 true 时丢弃缓冲区，交给外层的 panic 恢复中间件写响应
+// 截止时间已过 (超时回调可能还没来得及执行) 时按超时处理，panic 的情况同样写出 504
+// 返回是否已经超时或客户端已经断开
+func (w *timeoutWriter) finish(panicked bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.finished = true
+	if panicked {
+		w.buf.Reset()
+		return w.expiredLocked()
+	}
+	if w.expiredLocked() {
+		return true
+	}
+	w.commitLocked()
+	return false
+}
+
+// clientAbandoned 判断客户端是否在响应头发出之前断开
+func (w *timeoutWriter) clientAbandoned() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.abandoned
+}
+
+// timeout 在超时回调中调用，中间件已结束或已经处理过超时时什么也不做
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished || w.timedOut || w.closed {
+		return
+	}
+	w.timeoutLocked()
+}
+
+// clientClosed 在客户端断开 (请求 context 被取消) 时调用，中间件已结束或已经处理过时什么也不做
+func (w *timeoutWriter) clientClosed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finished || w.timedOut || w.closed {
+		return
+	}
+	w.closeLocked()
+}
+
+// closeLocked 标记客户端已断开并丢弃缓冲区，调用方必须持有 mu
+// 不在原始 writer 上写入 499: gin 在请求结束时会调用 WriteHeaderNow，写入的状态码会被真正发到连接上
+func (w *timeoutWriter) closeLocked() {
+	w.closed = true
+	w.abandoned = !w.committed
+	w.buf.Reset()
+	if w.onClosed != nil {
+		w.onClosed()
+	}
+}
+
+// timeoutLocked 标记超时并丢弃缓冲区，响应头尚未发出时写出 504，调用方必须持有 mu
+func (w *timeoutWriter) timeoutLocked() {
+	w.timedOut = true
+	wrote := false
+	if !w.committed {
+		w.buf.Reset()
+		h := w.ResponseWriter.Header()
+		h.Set("Content-Type", "application/json; charset=utf-8")
+		// 带上 Content-Length，客户端读完响应体即可结束，不必等待仍在运行的处理程序返回
+		h.Set("Content-Length", strconv.Itoa(len(w.body)))
+		w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+		_, _ = w.ResponseWriter.Write(w.body)
+		w.ResponseWriter.Flush()
+		w.committed = true
+		wrote = true
+	}
+	if w.onTimeout != nil {
+		w.onTimeout(wrote)
+	}
+}
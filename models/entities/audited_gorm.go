@@ -0,0 +1,102 @@
+package entities
+
+import (
+	"context"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+	"github.com/Xushengqwer/go-common/constants"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// optimisticLockSettingKey 是 BeforeUpdate 在 Statement.Settings 中记录更新前版本号的键名
+// 钩子收到的 tx 是 NewDB 会话，tx.InstanceSet 会作用在新的 Statement 上，因此直接读写当前 Statement 的 Settings
+const optimisticLockSettingKey = "go-common:optimistic_lock:version"
+
+// AuditedModel 带审计字段和乐观锁的基础模型 (主键为 Snowflake ID)
+// - CreatedBy / UpdatedBy: 由 GORM 钩子从 context 中的用户 ID 自动填充，需要使用 db.WithContext(ctx) 执行
+// - Version: 乐观锁版本号，创建时为 1，每次更新加 1；更新时版本号与数据库不一致返回 commonerrors.ErrVersionConflict
+//
+// 乐观锁只在 ID 和 Version 都已加载 (非零) 时生效，例如 First 查询后再 Save/Updates；
+// UpdateColumn、SkipHooks 或 Select 未包含 Version 的更新不会检查版本。
+// 嵌入该模型的结构体如果定义了自己的 BeforeCreate/BeforeUpdate/AfterUpdate，需要显式调用 AuditedModel 的同名方法。
+type AuditedModel struct {
+	SnowflakeModel
+	CreatedBy string `gorm:"size:64;index"` // 创建人用户 ID
+	UpdatedBy string `gorm:"size:64"`       // 最后修改人用户 ID
+	Version   int64  `gorm:"not null"`      // 乐观锁版本号
+}
+
+// BeforeCreate GORM 钩子: 生成 Snowflake ID、填充创建人/修改人并初始化版本号
+func (m *AuditedModel) BeforeCreate(tx *gorm.DB) error {
+	if err := m.SnowflakeModel.BeforeCreate(tx); err != nil {
+		return err
+	}
+	if userID := userIDFromContext(tx.Statement.Context); userID != "" {
+		if m.CreatedBy == "" {
+			m.CreatedBy = userID
+		}
+		if m.UpdatedBy == "" {
+			m.UpdatedBy = userID
+		}
+	}
+	if m.Version == 0 {
+		m.Version = 1
+	}
+	return nil
+}
+
+// BeforeUpdate GORM 钩子: 填充修改人，并为更新语句追加版本号条件 (WHERE version = 当前版本，SET version = 当前版本 + 1)
+func (m *AuditedModel) BeforeUpdate(tx *gorm.DB) error {
+	if userID := userIDFromContext(tx.Statement.Context); userID != "" {
+		tx.Statement.SetColumn("UpdatedBy", userID)
+	}
+
+	tx.Statement.Settings.Delete(optimisticLockSettingKey) // 清除克隆 Statement 时可能带过来的旧值
+	if m.ID == 0 || m.Version <= 0 || tx.Statement.Schema == nil {
+		return nil
+	}
+	field := tx.Statement.Schema.LookUpField("Version")
+	if field == nil {
+		return nil
+	}
+	current := m.Version
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: current},
+	}})
+	tx.Statement.SetColumn("Version", current+1)
+	tx.Statement.Settings.Store(optimisticLockSettingKey, current)
+	return nil
+}
+
+// AfterUpdate GORM 钩子: 带版本号条件的更新没有命中任何行时，说明数据已被其他请求修改
+// 返回 commonerrors.ErrVersionConflict (默认事务会回滚)，并把内存中的版本号恢复为更新前的值
+func (m *AuditedModel) AfterUpdate(tx *gorm.DB) error {
+	val, ok := tx.Statement.Settings.LoadAndDelete(optimisticLockSettingKey)
+	if !ok {
+		return nil
+	}
+	if previous, ok := val.(int64); ok && tx.Statement.RowsAffected == 0 {
+		m.Version = previous
+		return commonerrors.ErrVersionConflict
+	}
+	return nil
+}
+
+// WithActor 返回携带操作人用户 ID 的 context，AuditedModel 的钩子据此填充 CreatedBy / UpdatedBy
+// HTTP 请求中 UserContextMiddleware (identity.ContextWithUser) 已写入同一个键，无需再调用；
+// Kafka 消费者、定时任务等没有经过中间件的调用方可以用它指定操作人。
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, constants.UserIDKey, userID)
+}
+
+// userIDFromContext 读取 context 中的操作人用户 ID (constants.UserIDKey)
+// 在 Gin 处理器中应传入 c.Request.Context()：*gin.Context 默认不会把这些键转发到 request context。
+func userIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	userID, _ := ctx.Value(constants.UserIDKey).(string)
+	return userID
+}
@@ -0,0 +1,98 @@
+package entities
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Xushengqwer/go-common/commonerrors"
+	"github.com/Xushengqwer/go-common/config"
+	"github.com/Xushengqwer/go-common/constants"
+	"github.com/Xushengqwer/go-common/core/idgen"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type auditedPost struct {
+	AuditedModel
+	Title string
+}
+
+func openAuditedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	if err := idgen.Init(config.IDGenConfig{NodeID: 1}); err != nil {
+		t.Fatalf("idgen.Init: %v", err)
+	}
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开 SQLite 失败: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.AutoMigrate(&auditedPost{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return db
+}
+
+func TestAuditedModelFillsUserFromContext(t *testing.T) {
+	db := openAuditedDB(t)
+	ctx := WithActor(context.Background(), "u-1")
+
+	p := auditedPost{Title: "hello"}
+	if err := db.WithContext(ctx).Create(&p).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if p.ID == 0 || p.CreatedBy != "u-1" || p.UpdatedBy != "u-1" || p.Version != 1 {
+		t.Errorf("创建后的审计字段 = %+v", p.AuditedModel)
+	}
+
+	// 直接写入 constants.UserIDKey (UserContextMiddleware 的做法) 同样生效
+	ctx = context.WithValue(context.Background(), constants.UserIDKey, "u-2")
+	if err := db.WithContext(ctx).Model(&p).Updates(map[string]interface{}{"title": "edited"}).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	var got auditedPost
+	db.First(&got, p.ID)
+	if got.CreatedBy != "u-1" || got.UpdatedBy != "u-2" || got.Version != 2 {
+		t.Errorf("更新后的审计字段 = %+v", got.AuditedModel)
+	}
+}
+
+func TestAuditedModelIgnoresUntypedKey(t *testing.T) {
+	db := openAuditedDB(t)
+	// 故意使用未类型化的字符串键，确认不会被当作用户 ID
+	ctx := context.WithValue(context.Background(), "UserID", "forged")
+
+	p := auditedPost{Title: "hello"}
+	if err := db.WithContext(ctx).Create(&p).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if p.CreatedBy != "" {
+		t.Errorf("字符串键 \"UserID\" 不应被读取，CreatedBy = %q", p.CreatedBy)
+	}
+}
+
+func TestAuditedModelVersionConflict(t *testing.T) {
+	db := openAuditedDB(t)
+	p := auditedPost{Title: "hello"}
+	if err := db.Create(&p).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	stale := p
+	if err := db.Model(&p).Updates(map[string]interface{}{"title": "first"}).Error; err != nil {
+		t.Fatalf("Updates: %v", err)
+	}
+	err := db.Model(&stale).Updates(map[string]interface{}{"title": "second"}).Error
+	if !errors.Is(err, commonerrors.ErrVersionConflict) {
+		t.Errorf("过期版本的更新应返回 ErrVersionConflict，实际 %v", err)
+	}
+	if stale.Version != 1 {
+		t.Errorf("冲突后内存中的版本号应恢复为 1，实际 %d", stale.Version)
+	}
+}
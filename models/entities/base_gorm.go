@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"github.com/Xushengqwer/go-common/core/idgen"
 	"gorm.io/gorm"
 	"time"
 )
@@ -12,3 +13,48 @@ type BaseModel struct {
 	UpdatedAt time.Time      // 更新时间
 	DeletedAt gorm.DeletedAt `gorm:"index"` // 软删除时间戳，并添加索引
 }
+
+// SnowflakeModel 使用 Snowflake ID 作为主键的基础模型，ID 在多个分片/实例间全局唯一且按时间递增
+// - 创建时 ID 为 0 会由 BeforeCreate 钩子通过 idgen.NextID 生成 (服务启动时需调用 idgen.Init 配置节点 ID，否则创建返回 idgen.ErrNotInitialized)
+// - ID 超出 JavaScript 的安全整数范围，JSON 中以字符串输出
+type SnowflakeModel struct {
+	ID        uint64         `gorm:"primarykey;autoIncrement:false" json:"ID,string"` // Snowflake ID，不使用数据库自增
+	CreatedAt time.Time      // 创建时间
+	UpdatedAt time.Time      // 更新时间
+	DeletedAt gorm.DeletedAt `gorm:"index"` // 软删除时间戳，并添加索引
+}
+
+// BeforeCreate GORM 钩子: 未指定 ID 时生成 Snowflake ID
+func (m *SnowflakeModel) BeforeCreate(tx *gorm.DB) error {
+	if m.ID != 0 {
+		return nil
+	}
+	id, err := idgen.NextID()
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	return nil
+}
+
+// UUIDModel 使用 UUIDv7 字符串作为主键的基础模型，适合需要对外暴露且不可猜测的 ID
+// 创建时 ID 为空会由 BeforeCreate 钩子通过 idgen.NewUUIDv7 生成
+type UUIDModel struct {
+	ID        string         `gorm:"primarykey;size:36"` // UUIDv7 字符串
+	CreatedAt time.Time      // 创建时间
+	UpdatedAt time.Time      // 更新时间
+	DeletedAt gorm.DeletedAt `gorm:"index"` // 软删除时间戳，并添加索引
+}
+
+// BeforeCreate GORM 钩子: 未指定 ID 时生成 UUIDv7
+func (m *UUIDModel) BeforeCreate(tx *gorm.DB) error {
+	if m.ID != "" {
+		return nil
+	}
+	id, err := idgen.NewUUIDv7()
+	if err != nil {
+		return err
+	}
+	m.ID = id
+	return nil
+}
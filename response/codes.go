@@ -14,6 +14,7 @@ const (
 	ErrCodeClientRefreshTokenExpired = 40103 // Refresh Token 过期
 	ErrCodeClientForbidden           = 40301 // 客户端被禁止访问
 	ErrCodeClientResourceNotFound    = 40401 // 未找到指定资源
+	ErrCodeClientConflict            = 40901 // 资源状态冲突 (例如乐观锁版本冲突)
 	ErrCodeClientRateLimitExceeded   = 42901 // 请求频率超出限制
 
 	// 5xx 服务器错误
@@ -22,4 +23,5 @@ const (
 	ErrCodeServerTimeout          = 50002 // 操作超时
 	ErrCodeThirdPartyServiceError = 50004 // 外部第三方服务调用失败
 	ErrCodeServiceNotFound        = 50003 // 服务未找到（网关专用）
+	ErrCodeServerBusy             = 50005 // 服务繁忙
 )